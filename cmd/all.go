@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/database"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/migrations"
+	worker "github.com/nayeem-bd/Todo-App/internal/queue"
+	"github.com/nayeem-bd/Todo-App/internal/store"
+)
+
+// All runs the API and the worker in one process sharing one broker, which
+// makes the memory queue driver usable for local development.
+func All() {
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		logger.Fatal("Failed to load config:", err)
+	}
+
+	db, err := config.ConnectDatabase(cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to connect to database:", err)
+	}
+
+	// migrations
+	migrations.Migrate(db, cfg.Database.AutoMigrate)
+
+	cache, err := config.ConnectRedis(cfg.Redis)
+	if err != nil {
+		logger.Fatal("Failed to connect to Redis:", err)
+	}
+
+	defer cache.Close()
+
+	broker, err := worker.NewBroker(cfg.Queue, cfg.RabbitMQ)
+	if err != nil {
+		logger.Fatal("Failed to connect to the queue:", err)
+	}
+
+	defer broker.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cluster := connectCluster(ctx, cfg.Database, db)
+	watcher := config.NewWatcher(".", cfg)
+	go watcher.Watch(ctx)
+
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		worker.Work(ctx, store.New(cluster, database.NewTimeouts(cfg.Database)), cache, broker, cfg, watcher)
+	}()
+
+	runServer(ctx, newServer(cfg, watcher, cluster, cache, broker))
+	<-workerDone
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nayeem-bd/Todo-App/internal/config"
+)
+
+const configUsage = `Usage: go run main.go config <command>
+
+Commands:
+  check    print the effective config and where each value comes from, then validate it`
+
+func ConfigCommand(args []string) {
+	if len(args) != 1 || args[0] != "check" {
+		fmt.Println(configUsage)
+		os.Exit(2)
+	}
+
+	cfg, settings, err := config.Inspect(".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	printSettings(settings)
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "\n"+err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("\nConfig is valid")
+}
+
+func printSettings(settings []config.Setting) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tENV")
+	for _, setting := range settings {
+		env := setting.Env
+		if env == "" {
+			env = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", setting.Key, setting.Value, setting.Source, env)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/database"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"gorm.io/gorm"
+)
+
+// connectCluster puts the configured read replicas behind db and checks their
+// health until ctx is cancelled.
+func connectCluster(ctx context.Context, dbConfig config.DatabaseConfig, db *gorm.DB) *database.Cluster {
+	replicas, err := config.ConnectReplicas(dbConfig)
+	if err != nil {
+		logger.Fatal("Failed to connect to read replicas:", err)
+	}
+
+	cluster := database.NewCluster(db, replicas...)
+	go cluster.Monitor(ctx, time.Duration(dbConfig.ReplicaCheckInterval)*time.Second)
+	return cluster
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	worker "github.com/nayeem-bd/Todo-App/internal/queue"
+)
+
+const dlqUsage = `Usage: go run main.go dlq <command> [flags]
+
+Commands:
+  list     show dead-lettered messages
+  replay   republish messages to the main exchange
+  purge    delete messages from the dead-letter queue`
+
+func DLQ(args []string) {
+	if len(args) < 1 {
+		fmt.Println(dlqUsage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("dlq "+args[0], flag.ExitOnError)
+	limit := fs.Int("limit", 100, "maximum number of messages to list")
+	asJSON := fs.Bool("json", false, "print messages as JSON")
+	all := fs.Bool("all", false, "select every message")
+	ids := fs.String("ids", "", "comma-separated message IDs to select")
+	_ = fs.Parse(args[1:])
+
+	selector := worker.DLQSelector{All: *all}
+	if *ids != "" {
+		selector.IDs = strings.Split(*ids, ",")
+	}
+
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		logger.Fatal("Failed to load config:", err)
+	}
+
+	if cfg.Queue.Driver == config.QueueDriverMemory {
+		logger.Fatal("queue.driver memory keeps dead letters in the `all` process, use the /admin/dlq endpoints instead")
+	}
+
+	queue, err := config.SetupRabbitMQConnection(cfg.RabbitMQ)
+	if err != nil {
+		logger.Fatal("Failed to connect to RabbitMQ:", err)
+	}
+	defer queue.Close()
+
+	dlq := worker.NewDLQ(queue)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "list":
+		letters, err := dlq.List(ctx, *limit)
+		if err != nil {
+			logger.Fatal("Failed to list dead-lettered messages:", err)
+		}
+		if *asJSON {
+			_ = json.NewEncoder(os.Stdout).Encode(letters)
+			return
+		}
+		printDeadLetters(letters)
+	case "replay", "purge":
+		if !selector.All && len(selector.IDs) == 0 {
+			fmt.Println("Select messages with -ids or -all")
+			os.Exit(2)
+		}
+		action, run := "Replayed", dlq.Replay
+		if args[0] == "purge" {
+			action, run = "Purged", dlq.Purge
+		}
+		count, err := run(ctx, selector)
+		if err != nil {
+			logger.Fatal("Failed to "+args[0]+" dead-lettered messages:", err)
+		}
+		fmt.Printf("%s %d message(s)\n", action, count)
+	default:
+		fmt.Println(dlqUsage)
+		os.Exit(2)
+	}
+}
+
+func printDeadLetters(letters []worker.DeadLetter) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEVENT\tTODO ID\tATTEMPTS\tFAILED AT\tREASON")
+	for _, letter := range letters {
+		todoID := "-"
+		if letter.TodoID != nil {
+			todoID = fmt.Sprint(*letter.TodoID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			letter.ID, letter.Event, todoID, letter.Attempts, letter.FailedAt, letter.Reason)
+	}
+	_ = w.Flush()
+	fmt.Printf("%d message(s)\n", len(letters))
+}
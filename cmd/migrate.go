@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/migrations"
+)
+
+const migrateUsage = `Usage: go run main.go migrate <command> [flags]
+
+Commands:
+  up       apply every pending migration
+  down     roll back the last migration, or -steps of them
+  status   list migrations and whether they are applied
+  create   write empty up and down files: migrate create <name>
+  force    mark migrations up to <version> applied and clean, and later ones
+           pending, without running SQL: migrate force <version>`
+
+func Migrate(args []string) {
+	if len(args) < 1 {
+		fmt.Println(migrateUsage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	steps := fs.Int("steps", 1, "number of migrations to roll back")
+	dir := fs.String("dir", migrations.Dir, "directory new migrations are written to")
+	_ = fs.Parse(args[1:])
+
+	if args[0] == "create" {
+		if fs.NArg() != 1 {
+			fmt.Println("Usage: go run main.go migrate create [-dir DIR] <name>")
+			os.Exit(2)
+		}
+		up, down, err := migrations.Create(*dir, fs.Arg(0))
+		if err != nil {
+			logger.Fatal("Failed to create migration:", err)
+		}
+		fmt.Printf("Created %s\nCreated %s\n", up, down)
+		return
+	}
+
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		logger.Fatal("Failed to load config:", err)
+	}
+
+	db, err := config.ConnectDatabase(cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to connect to database:", err)
+	}
+
+	embedded, err := migrations.Embedded(db.Dialector.Name())
+	if err != nil {
+		logger.Fatal("Failed to load migrations:", err)
+	}
+	migrator := migrations.New(db, embedded)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			logger.Fatal("Failed to apply migrations:", err)
+		}
+		fmt.Printf("Applied %d migration(s)\n", count)
+	case "down":
+		count, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			logger.Fatal("Failed to roll back migrations:", err)
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", count)
+	case "force":
+		version, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+		if fs.NArg() != 1 || err != nil || version < 0 {
+			fmt.Println("Usage: go run main.go migrate force <version>")
+			os.Exit(2)
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			logger.Fatal("Failed to force migration version:", err)
+		}
+		fmt.Printf("Forced schema to version %d\n", version)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			logger.Fatal("Failed to read migration status:", err)
+		}
+		printMigrations(statuses)
+	default:
+		fmt.Println(migrateUsage)
+		os.Exit(2)
+	}
+}
+
+func printMigrations(statuses []migrations.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.Applied {
+			state = "applied"
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Dirty {
+			state = "dirty"
+		}
+		if status.Applied && status.Up == "" {
+			state += " (unknown to this release)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/database"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	worker "github.com/nayeem-bd/Todo-App/internal/queue"
+	"github.com/nayeem-bd/Todo-App/internal/store"
+)
+
+// Relay runs only the outbox relay, for deployments that scale it
+// separately from the worker.
+func Relay() {
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		logger.Fatal("Failed to load config:", err)
+	}
+
+	if cfg.Queue.Driver == config.QueueDriverMemory {
+		logger.Fatal("queue.driver memory only works within one process, use `all` instead of `relay`")
+	}
+
+	db, err := config.ConnectDatabase(cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to connect to database:", err)
+	}
+
+	queue, err := config.SetupRabbitMQConnection(cfg.RabbitMQ)
+	if err != nil {
+		logger.Fatal("Failed to connect to RabbitMQ:", err)
+	}
+	defer queue.Close()
+
+	publisher := worker.NewRabbitPublisher(queue)
+	defer publisher.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Only the log level applies to the relay
+	go config.NewWatcher(".", cfg).Watch(ctx)
+
+	worker.NewOutboxRelay(store.New(database.NewCluster(db), database.NewTimeouts(cfg.Database)), publisher, cfg.Outbox).Run(ctx)
+}
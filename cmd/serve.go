@@ -13,9 +13,14 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	appHttp "github.com/nayeem-bd/Todo-App/http"
 	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/database"
+	"github.com/nayeem-bd/Todo-App/internal/health"
 	"github.com/nayeem-bd/Todo-App/internal/logger"
 	customMiddleware "github.com/nayeem-bd/Todo-App/internal/middleware"
 	"github.com/nayeem-bd/Todo-App/internal/migrations"
+	worker "github.com/nayeem-bd/Todo-App/internal/queue"
+	"github.com/nayeem-bd/Todo-App/internal/store"
+	"github.com/nayeem-bd/Todo-App/modules/todo/usecase"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -31,24 +36,44 @@ func Serve() {
 	}
 
 	// migrations
-	migrations.Migrate(db)
+	migrations.Migrate(db, cfg.Database.AutoMigrate)
 
-	cache := config.ConnectRedis(cfg.Redis)
+	cache, err := config.ConnectRedis(cfg.Redis)
+	if err != nil {
+		logger.Fatal("Failed to connect to Redis:", err)
+	}
 
 	defer cache.Close()
 
-	queue, err := config.SetupRabbitMQConnection(cfg.RabbitMQ, cfg.Server)
+	if cfg.Queue.Driver == config.QueueDriverMemory {
+		logger.Warn("queue.driver is memory: events are only consumed when the worker runs in this process, use `all`")
+	}
 
+	broker, err := worker.NewBroker(cfg.Queue, cfg.RabbitMQ)
 	if err != nil {
-		logger.Fatal("Failed to connect to RabbitMQ:", err)
+		logger.Fatal("Failed to connect to the queue:", err)
 	}
 
+	defer broker.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watcher := config.NewWatcher(".", cfg)
+	go watcher.Watch(ctx)
+
+	runServer(ctx, newServer(cfg, watcher, connectCluster(ctx, cfg.Database, db), cache, broker))
+}
+
+func newServer(cfg *config.Config, watcher *config.Watcher, cluster *database.Cluster, cache *config.Cache, broker worker.Broker) *http.Server {
 	addr := fmt.Sprintf(":%s", cfg.Server.Port)
 
 	r := chi.NewRouter()
 
 	//middlewares
 	r.Use(middleware.RequestID)
+	r.Use(customMiddleware.Correlation)
+	r.Use(customMiddleware.ReadYourWrites)
 	r.Use(customMiddleware.Logger)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Recoverer)
@@ -56,32 +81,50 @@ func Serve() {
 	r.Use(customMiddleware.Prometheus)
 	r.Handle("/metrics", promhttp.Handler())
 
-	handler := appHttp.RegisterHandlers(db, cache, queue)
-	appHttp.SetupRouter(r, handler)
+	checks := health.New(
+		health.DatabaseCheck(cluster.Primary()),
+		health.Check{Name: "cache", Probe: cache.Ping},
+		health.Check{Name: "queue", Probe: broker.Ping},
+	)
+	r.Get("/health/live", checks.Live)
+	r.Get("/health/ready", checks.Ready)
+
+	completion := usecase.Completion{
+		Strategy:    cfg.Completion.Strategy,
+		WaitTimeout: time.Duration(cfg.Completion.WaitTimeout) * time.Second,
+		BrokerUp:    broker.Ping,
+	}
+	handler := appHttp.RegisterHandlers(store.New(cluster, database.NewTimeouts(cfg.Database)), cache, broker.DeadLetters(), completion)
+	appHttp.SetupRouter(r, handler, cfg.Server.AdminToken)
+
+	handler.TodoUsecase.SetCacheTTL(time.Duration(cfg.Cache.TodosTTL) * time.Second)
+	watcher.OnChange(func(cfg *config.Config) {
+		handler.TodoUsecase.SetCacheTTL(time.Duration(cfg.Cache.TodosTTL) * time.Second)
+	})
 
-	srv := &http.Server{Addr: addr, Handler: r, ReadTimeout: 10 * time.Second, WriteTimeout: 10 * time.Second, IdleTimeout: 120 * time.Second}
+	return &http.Server{Addr: addr, Handler: r, ReadTimeout: 10 * time.Second, WriteTimeout: config.ServerWriteTimeout, IdleTimeout: 120 * time.Second}
+}
 
-	go shutdownServer(srv)
+// runServer serves until ctx is cancelled and then shuts down gracefully.
+func runServer(ctx context.Context, srv *http.Server) {
+	go shutdownServer(ctx, srv)
 
 	//fmt.Printf("Server is running on http://localhost%s\n", addr)
-	logger.Info("Starting server on", addr)
+	logger.Info("Starting server on", srv.Addr)
 
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		logger.Fatal("Failed to start server:", err)
 	}
 }
 
-func shutdownServer(srv *http.Server) {
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-
-	<-quit
+func shutdownServer(ctx context.Context, srv *http.Server) {
+	<-ctx.Done()
 	logger.Info("Shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Error("Error shutting down server:", err)
 	}
 	logger.Info("Server gracefully stopped")
@@ -1,9 +1,24 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/database"
+	"github.com/nayeem-bd/Todo-App/internal/health"
 	"github.com/nayeem-bd/Todo-App/internal/logger"
 	worker "github.com/nayeem-bd/Todo-App/internal/queue"
+	"github.com/nayeem-bd/Todo-App/internal/store"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
 )
 
 func Work() {
@@ -12,18 +27,65 @@ func Work() {
 		logger.Fatal("Failed to load config:", err)
 	}
 
+	if cfg.Queue.Driver == config.QueueDriverMemory {
+		logger.Fatal("queue.driver memory only works within one process, use `all` instead of `work`")
+	}
+
 	db, err := config.ConnectDatabase(cfg.Database)
 	if err != nil {
 		logger.Fatal("Failed to connect to database:", err)
 	}
 
-	cache := config.ConnectRedis(cfg.Redis)
+	cache, err := config.ConnectRedis(cfg.Redis)
+	if err != nil {
+		logger.Fatal("Failed to connect to Redis:", err)
+	}
+
+	defer cache.Close()
 
-	queue, err := config.SetupRabbitMQConnection(cfg.RabbitMQ, cfg.Server)
+	broker, err := worker.NewBroker(cfg.Queue, cfg.RabbitMQ)
 
 	if err != nil {
-		logger.Fatal("Failed to connect to RabbitMQ:", err)
+		logger.Fatal("Failed to connect to the queue:", err)
+	}
+
+	defer broker.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.Worker.AdminPort != "" {
+		go runServer(ctx, newAdminServer(cfg.Worker, db, cache, broker))
 	}
 
-	worker.Work(db, cache, queue)
+	watcher := config.NewWatcher(".", cfg)
+	go watcher.Watch(ctx)
+
+	worker.Work(ctx, store.New(connectCluster(ctx, cfg.Database, db), database.NewTimeouts(cfg.Database)), cache, broker, cfg, watcher)
+}
+
+// newAdminServer serves the worker's metrics and probes. Readiness follows
+// the RabbitMQ connection; liveness only fails once the connection has been
+// down for longer than the reconnect loop should need.
+func newAdminServer(workerConfig config.WorkerConfig, db *gorm.DB, cache *config.Cache, broker worker.Broker) *http.Server {
+	addr := fmt.Sprintf(":%s", workerConfig.AdminPort)
+
+	r := chi.NewRouter()
+	r.Use(middleware.Recoverer)
+	r.Handle("/metrics", promhttp.Handler())
+
+	checks := health.New(
+		health.DatabaseCheck(db),
+		health.Check{Name: "cache", Probe: cache.Ping},
+		health.Check{Name: "queue", Critical: true, Probe: broker.Ping},
+		health.Check{
+			Name:     "queue_connection",
+			Liveness: true,
+			Probe:    health.Sustained(broker.Ping, time.Duration(workerConfig.DisconnectGrace)*time.Second),
+		},
+	)
+	r.Get("/health/live", checks.Live)
+	r.Get("/health/ready", checks.Ready)
+
+	return &http.Server{Addr: addr, Handler: r, ReadTimeout: 10 * time.Second, WriteTimeout: 10 * time.Second, IdleTimeout: 120 * time.Second}
 }
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrCacheMiss        = errors.New("cache miss")
+	ErrCacheUnavailable = errors.New("cache unavailable")
+)
+
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value string, expiration time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
@@ -1,6 +1,136 @@
 package dto
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	CloudEventsContentType = "application/cloudevents+json"
+	EventSource            = "/todo-app"
+)
+
+// Event types and the data versions the worker understands
+const (
+	EventTodoCompleted   = "com.todoapp.todo.completed"
+	EventTodoSnoozeEnded = "com.todoapp.todo.snooze_ended"
+
+	TodoCompletedDataVersion   = "1"
+	TodoSnoozeEndedDataVersion = "1"
+)
+
+// legacyEventTypes maps the event names used before the envelope existed.
+var legacyEventTypes = map[string]string{
+	"todo_completed": EventTodoCompleted,
+}
+
+// Envelope follows the CloudEvents 1.0 JSON format. Correlation ID, tenant
+// and data version are carried as extension attributes.
+type Envelope struct {
+	ID              string          `json:"id"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	SpecVersion     string          `json:"specversion"`
+	Time            time.Time       `json:"time"`
+	Subject         string          `json:"subject,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	DataVersion     string          `json:"dataversion"`
+	CorrelationID   string          `json:"correlationid,omitempty"`
+	Tenant          string          `json:"tenant,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
+
+type TodoCompletedData struct {
+	TodoID int `json:"todo_id"`
+}
+
+type TodoSnoozeEndedData struct {
+	TodoID int       `json:"todo_id"`
+	Until  time.Time `json:"until"`
+}
+
+// Event is the legacy message shape, still accepted by the consumer while
+// producers migrate to Envelope.
 type Event struct {
 	Event  string `json:"event" validate:"required"`
 	TodoID *int   `json:"todo_id,omitempty"`
 }
+
+func NewEnvelope(id, eventType, dataVersion, subject string, data interface{}) (*Envelope, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event data: %w", err)
+	}
+	return &Envelope{
+		ID:              id,
+		Type:            eventType,
+		Source:          EventSource,
+		SpecVersion:     CloudEventsSpecVersion,
+		Time:            time.Now().UTC(),
+		Subject:         subject,
+		DataContentType: "application/json",
+		DataVersion:     dataVersion,
+		Data:            raw,
+	}, nil
+}
+
+// DecodeEvent reads either an Envelope or the legacy Event shape, which is
+// converted to an envelope with version 1 data.
+func DecodeEvent(body []byte) (*Envelope, error) {
+	var probe struct {
+		SpecVersion string `json:"specversion"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	if probe.SpecVersion != "" {
+		var envelope Envelope
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, fmt.Errorf("failed to decode event envelope: %w", err)
+		}
+		if envelope.SpecVersion != CloudEventsSpecVersion {
+			return nil, fmt.Errorf("unsupported specversion: %s", envelope.SpecVersion)
+		}
+		if envelope.Type == "" {
+			return nil, fmt.Errorf("event type is required")
+		}
+		return &envelope, nil
+	}
+
+	var legacy Event
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return nil, fmt.Errorf("failed to decode legacy event: %w", err)
+	}
+	eventType, ok := legacyEventTypes[legacy.Event]
+	if !ok {
+		eventType = legacy.Event
+	}
+
+	data := map[string]interface{}{}
+	if legacy.TodoID != nil {
+		data["todo_id"] = *legacy.TodoID
+	}
+	raw, _ := json.Marshal(data)
+	return &Envelope{
+		Type:            eventType,
+		Source:          EventSource,
+		SpecVersion:     CloudEventsSpecVersion,
+		DataContentType: "application/json",
+		DataVersion:     "1",
+		Data:            raw,
+	}, nil
+}
+
+// DecodeData unmarshals the payload after checking its version.
+func (e *Envelope) DecodeData(version string, v interface{}) error {
+	if e.DataVersion != version {
+		return fmt.Errorf("unsupported data version %q for %s", e.DataVersion, e.Type)
+	}
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("failed to decode %s data: %w", e.Type, err)
+	}
+	return nil
+}
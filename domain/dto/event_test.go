@@ -0,0 +1,85 @@
+package dto
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeEvent(t *testing.T) {
+	envelope, err := NewEnvelope("evt-1", EventTodoCompleted, TodoCompletedDataVersion, "todos/7", TodoCompletedData{TodoID: 7})
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	envelope.CorrelationID = "corr-1"
+	encoded, _ := json.Marshal(envelope)
+
+	tests := []struct {
+		name              string
+		body              string
+		wantErr           bool
+		wantType          string
+		wantCorrelationID string
+		wantTodoID        int
+	}{
+		{
+			name:              "envelope",
+			body:              string(encoded),
+			wantType:          EventTodoCompleted,
+			wantCorrelationID: "corr-1",
+			wantTodoID:        7,
+		},
+		{
+			name:       "legacy event",
+			body:       `{"event":"todo_completed","todo_id":7}`,
+			wantType:   EventTodoCompleted,
+			wantTodoID: 7,
+		},
+		{
+			name:    "unsupported specversion",
+			body:    `{"specversion":"0.3","type":"com.todoapp.todo.completed"}`,
+			wantErr: true,
+		},
+		{
+			name:    "envelope without type",
+			body:    `{"specversion":"1.0","id":"evt-1"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			body:    `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := DecodeEvent([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if event.Type != tt.wantType || event.CorrelationID != tt.wantCorrelationID {
+				t.Errorf("DecodeEvent() type = %q, correlation id = %q", event.Type, event.CorrelationID)
+			}
+
+			var data TodoCompletedData
+			if err := event.DecodeData(TodoCompletedDataVersion, &data); err != nil {
+				t.Fatalf("DecodeData() error = %v", err)
+			}
+			if data.TodoID != tt.wantTodoID {
+				t.Errorf("DecodeData() todo_id = %d, want %d", data.TodoID, tt.wantTodoID)
+			}
+		})
+	}
+}
+
+func TestEnvelope_DecodeDataVersionMismatch(t *testing.T) {
+	envelope, _ := NewEnvelope("evt-1", EventTodoCompleted, "2", "todos/7", TodoCompletedData{TodoID: 7})
+
+	var data TodoCompletedData
+	if err := envelope.DecodeData(TodoCompletedDataVersion, &data); err == nil {
+		t.Error("DecodeData() should reject an unknown data version")
+	}
+}
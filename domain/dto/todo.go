@@ -1,6 +1,10 @@
 package dto
 
-import "github.com/nayeem-bd/Todo-App/domain"
+import (
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+)
 
 type CreateTodoRequest struct {
 	Title       string `json:"title" validate:"required,min=3,max=150"`
@@ -15,3 +19,7 @@ func (req *CreateTodoRequest) ToDomain() *domain.Todo {
 		Category:    req.Category,
 	}
 }
+
+type SnoozeTodoRequest struct {
+	Until time.Time `json:"until" validate:"required"`
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "errors"
+
+// Repositories return these instead of driver errors, wrapping the cause.
+var (
+	ErrNotFound = errors.New("record not found")
+	ErrConflict = errors.New("record conflicts with an existing one")
+	ErrTimeout  = errors.New("database operation timed out")
+)
+
+// PermanentError marks a failure that will not go away by retrying, such as
+// a malformed event or a reference to a todo that does not exist.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
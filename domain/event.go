@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrPublishNacked     = errors.New("broker did not accept the message")
+	ErrPublishUnroutable = errors.New("message could not be routed to any queue")
+	ErrPublishTimeout    = errors.New("timed out waiting for the broker")
+)
+
+// Message is an encoded event ready to be published. An empty RoutingKey
+// uses the publisher's default. Type is sent as the AMQP type property, so
+// consumers can tell events apart without decoding them. A positive Delay
+// asks the broker to hold the message before routing it.
+type Message struct {
+	RoutingKey  string
+	ContentType string
+	MessageID   string
+	Type        string
+	Body        []byte
+	Delay       time.Duration
+}
+
+// EventPublisher returns only once the broker has taken responsibility for
+// the message.
+type EventPublisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// OutboxEvent is an event stored in the same transaction as the change that
+// produced it and published later by the outbox relay.
+type OutboxEvent struct {
+	ID          int64      `json:"id" gorm:"primaryKey"`
+	EventID     string     `json:"event_id" gorm:"type:varchar(36);uniqueIndex;not null"`
+	EventType   string     `json:"event_type" gorm:"type:varchar(100);not null"`
+	RoutingKey  string     `json:"routing_key" gorm:"type:varchar(255)"`
+	ContentType string     `json:"content_type" gorm:"type:varchar(100)"`
+	Payload     []byte     `json:"payload" gorm:"not null"`
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	LastError   string     `json:"last_error" gorm:"type:text"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	AvailableAt time.Time  `json:"available_at" gorm:"not null"`
+	SentAt      *time.Time `json:"sent_at" gorm:"index"`
+	// FailedAt is set when the relay gives up on the event
+	FailedAt *time.Time `json:"failed_at"`
+}
+
+func (e *OutboxEvent) TableName() string {
+	return "outbox"
+}
+
+type OutboxRepository interface {
+	Add(ctx context.Context, event *OutboxEvent) (*OutboxEvent, error)
+	// FetchPending locks and returns unsent events that are due for a
+	// retry, in insertion order. Parked events are skipped.
+	FetchPending(ctx context.Context, limit int) ([]*OutboxEvent, error)
+	MarkSent(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, lastError string, availableAt time.Time) error
+	// MarkParked records a final failed attempt; the relay no longer
+	// publishes the event
+	MarkParked(ctx context.Context, id int64, lastError string) error
+}
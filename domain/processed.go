@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ProcessedMessage records that a consumer has applied a message, so a
+// redelivery of the same message can be skipped.
+type ProcessedMessage struct {
+	Consumer    string    `json:"consumer" gorm:"primaryKey;type:varchar(100)"`
+	MessageID   string    `json:"message_id" gorm:"primaryKey;type:varchar(64)"`
+	ProcessedAt time.Time `json:"processed_at" gorm:"not null;index"`
+}
+
+func (m *ProcessedMessage) TableName() string {
+	return "processed_messages"
+}
+
+type ProcessedMessageRepository interface {
+	// MarkProcessed records the message and reports false if the consumer
+	// had already processed it. Call it in the transaction of the side
+	// effect so both commit or roll back together.
+	MarkProcessed(ctx context.Context, consumer, messageID string) (bool, error)
+	// DeleteBefore removes records older than before and returns how many
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
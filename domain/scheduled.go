@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ScheduledMessage is an event to publish at DueAt. It is stored in the
+// same transaction as the change that scheduled it and handed to the broker
+// by the scheduler shortly before it is due.
+type ScheduledMessage struct {
+	ID          int64      `json:"id" gorm:"primaryKey"`
+	MessageID   string     `json:"message_id" gorm:"type:varchar(36);uniqueIndex;not null"`
+	EventType   string     `json:"event_type" gorm:"type:varchar(100);not null"`
+	RoutingKey  string     `json:"routing_key" gorm:"type:varchar(255)"`
+	ContentType string     `json:"content_type" gorm:"type:varchar(100)"`
+	Payload     []byte     `json:"payload" gorm:"not null"`
+	DueAt       time.Time  `json:"due_at" gorm:"not null;index"`
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	LastError   string     `json:"last_error" gorm:"type:text"`
+	AvailableAt time.Time  `json:"available_at" gorm:"not null"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	SentAt      *time.Time `json:"sent_at" gorm:"index"`
+}
+
+func (m *ScheduledMessage) TableName() string {
+	return "scheduled_messages"
+}
+
+type ScheduledMessageRepository interface {
+	Add(ctx context.Context, msg *ScheduledMessage) (*ScheduledMessage, error)
+	// FetchDue locks and returns unsent messages due before the given time
+	// whose retry backoff has passed, earliest first
+	FetchDue(ctx context.Context, before time.Time, limit int) ([]*ScheduledMessage, error)
+	MarkSent(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, lastError string, availableAt time.Time) error
+}
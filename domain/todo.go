@@ -2,9 +2,15 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+var (
+	ErrTodoCompleted     = errors.New("todo is already completed")
+	ErrCompletionPending = errors.New("todo completion is still pending")
+)
+
 type Todo struct {
 	ID          int        `json:"id" gorm:"primaryKey"`
 	Title       string     `json:"title" gorm:"type:varchar(100);not null"`
@@ -13,16 +19,27 @@ type Todo struct {
 	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 	DoneAt      *time.Time `json:"done_at" gorm:"type:timestamp;default:null"`
+	// SnoozedUntil is set while the todo is snoozed
+	SnoozedUntil *time.Time `json:"snoozed_until" gorm:"type:timestamp;default:null"`
 }
 
 func (t *Todo) TableName() string {
 	return "todos"
 }
 
+// TodoRepository returns ErrNotFound for a missing todo and ErrTimeout when
+// an operation exceeds its timeout.
 type TodoRepository interface {
+	// GetAll returns every todo that is not snoozed, ordered by ID
 	GetAll(ctx context.Context) ([]*Todo, error)
+	// List returns a page of at most limit todos with an ID above afterID
+	// that are not snoozed, ordered by ID. Pass the last ID of a page to get
+	// the next one.
+	List(ctx context.Context, afterID, limit int) ([]*Todo, error)
 	Create(ctx context.Context, todo *Todo) (*Todo, error)
 	GetByID(ctx context.Context, id int) (*Todo, error)
+	// GetByIDForUpdate locks the row until the surrounding transaction ends
+	GetByIDForUpdate(ctx context.Context, id int) (*Todo, error)
 	Update(ctx context.Context, todo *Todo) (*Todo, error)
 }
 
@@ -30,6 +47,10 @@ type TodoUsecase interface {
 	GetAll(ctx context.Context) ([]*Todo, error)
 	Create(ctx context.Context, todo *Todo) (*Todo, error)
 	GetByID(ctx context.Context, id int) (*Todo, error)
-	Complete(ctx context.Context, id int) error
-	CompleteTodo(ctx context.Context, id int) error
+	// Complete with wait blocks until the todo is completed, or returns
+	// ErrCompletionPending once the wait times out.
+	Complete(ctx context.Context, id int, wait bool) (*Todo, error)
+	CompleteTodo(ctx context.Context, eventID string, id int) error
+	Snooze(ctx context.Context, id int, until time.Time) (*Todo, error)
+	EndSnooze(ctx context.Context, eventID string, id int, until time.Time) error
 }
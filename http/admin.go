@@ -0,0 +1,80 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nayeem-bd/Todo-App/internal/queue"
+	"github.com/nayeem-bd/Todo-App/internal/utils"
+)
+
+type AdminHandler struct {
+	dlq queue.DeadLetterQueue
+}
+
+func NewAdminHandler(dlq queue.DeadLetterQueue) *AdminHandler {
+	return &AdminHandler{dlq: dlq}
+}
+
+func (adminHandler *AdminHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 0 {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid limit", nil)
+			return
+		}
+	}
+
+	letters, err := adminHandler.dlq.List(r.Context(), limit)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to list dead-lettered messages", err.Error())
+		return
+	}
+
+	utils.WriteSuccess(w, http.StatusOK, "Dead-lettered messages retrieved successfully", letters)
+}
+
+func (adminHandler *AdminHandler) ReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	selector, ok := decodeSelector(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := adminHandler.dlq.Replay(r.Context(), selector)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to replay dead-lettered messages", err.Error())
+		return
+	}
+
+	utils.WriteSuccess(w, http.StatusOK, "Dead-lettered messages replayed successfully", map[string]int{"replayed": count})
+}
+
+func (adminHandler *AdminHandler) PurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	selector, ok := decodeSelector(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := adminHandler.dlq.Purge(r.Context(), selector)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to purge dead-lettered messages", err.Error())
+		return
+	}
+
+	utils.WriteSuccess(w, http.StatusOK, "Dead-lettered messages purged successfully", map[string]int{"purged": count})
+}
+
+func decodeSelector(w http.ResponseWriter, r *http.Request) (queue.DLQSelector, bool) {
+	var selector queue.DLQSelector
+	if err := json.NewDecoder(r.Body).Decode(&selector); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return selector, false
+	}
+	if !selector.All && len(selector.IDs) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, "Validation failed", map[string]string{"ids": "ids is required unless all is set"})
+		return selector, false
+	}
+	return selector, true
+}
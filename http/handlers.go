@@ -1,23 +1,27 @@
 package http
 
 import (
-	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/domain"
+	worker "github.com/nayeem-bd/Todo-App/internal/queue"
 	"github.com/nayeem-bd/Todo-App/internal/store"
 	handler "github.com/nayeem-bd/Todo-App/modules/todo/delivery/http"
 	"github.com/nayeem-bd/Todo-App/modules/todo/usecase"
-	"gorm.io/gorm"
 )
 
 type Handler struct {
-	TodoHandler *handler.TodoHandler
+	TodoHandler  *handler.TodoHandler
+	AdminHandler *AdminHandler
+	// TodoUsecase is exposed so runtime settings can be applied to it
+	TodoUsecase *usecase.TodoUsecase
 }
 
-func RegisterHandlers(db *gorm.DB, cache *config.Cache, queue *config.Queue) *Handler {
-	s := store.New(db)
-
-	todoUsecase := usecase.NewTodoUsecase(s, cache, queue)
+func RegisterHandlers(s store.Store, cache domain.Cache, dlq worker.DeadLetterQueue, completion usecase.Completion) *Handler {
+	todoUsecase := usecase.NewTodoUsecase(s, cache)
+	todoUsecase.SetCompletion(completion)
 
 	return &Handler{
-		TodoHandler: handler.NewTodoHandler(todoUsecase),
+		TodoHandler:  handler.NewTodoHandler(todoUsecase),
+		AdminHandler: NewAdminHandler(dlq),
+		TodoUsecase:  todoUsecase,
 	}
 }
@@ -2,18 +2,32 @@ package http
 
 import (
 	"github.com/go-chi/chi/v5"
+	"github.com/nayeem-bd/Todo-App/internal/middleware"
 	"net/http"
 )
 
-func SetupRouter(r *chi.Mux, h *Handler) http.Handler {
+// SetupRouter mounts the admin routes only when an admin token is configured.
+func SetupRouter(r *chi.Mux, h *Handler, adminToken string) http.Handler {
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Route("/todos", func(r chi.Router) {
 			r.Get("/", h.TodoHandler.GetTodos)
 			r.Post("/", h.TodoHandler.CreateTodo)
 			r.Get("/{id}", h.TodoHandler.GetTodoByID)
 			r.Post("/{id}/complete", h.TodoHandler.CompleteTodo)
+			r.Post("/{id}/snooze", h.TodoHandler.SnoozeTodo)
 		})
 	})
 
+	if adminToken != "" {
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.AdminAuth(adminToken))
+			r.Route("/dlq", func(r chi.Router) {
+				r.Get("/", h.AdminHandler.ListDeadLetters)
+				r.Post("/replay", h.AdminHandler.ReplayDeadLetters)
+				r.Post("/purge", h.AdminHandler.PurgeDeadLetters)
+			})
+		})
+	}
+
 	return r
 }
@@ -0,0 +1,69 @@
+package config
+
+import "sync"
+
+// circuitBreaker opens after a run of consecutive failures and stays open
+// until a success is reported, which the cache reconnect loop does once the
+// backend answers again.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	failures  int
+	open      bool
+	onChange  func(open bool)
+}
+
+func newCircuitBreaker(threshold int, onChange func(open bool)) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &circuitBreaker{threshold: threshold, onChange: onChange}
+}
+
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.open
+}
+
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	b.failures = 0
+	changed := b.open
+	b.open = false
+	b.mu.Unlock()
+
+	if changed && b.onChange != nil {
+		b.onChange(false)
+	}
+}
+
+// Failure records a failed call and reports whether it tripped the breaker.
+func (b *circuitBreaker) Failure() bool {
+	b.mu.Lock()
+	b.failures++
+	tripped := !b.open && b.failures >= b.threshold
+	if tripped {
+		b.open = true
+	}
+	b.mu.Unlock()
+
+	if tripped && b.onChange != nil {
+		b.onChange(true)
+	}
+	return tripped
+}
+
+// Trip opens the breaker regardless of the failure count.
+func (b *circuitBreaker) Trip() bool {
+	b.mu.Lock()
+	b.failures = b.threshold
+	tripped := !b.open
+	b.open = true
+	b.mu.Unlock()
+
+	if tripped && b.onChange != nil {
+		b.onChange(true)
+	}
+	return tripped
+}
@@ -1,106 +1,486 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/secrets"
 	"github.com/spf13/viper"
 )
 
+// Config is the whole application config. String settings may contain
+// ${secret:<provider>:<ref>} references; credentials are tagged
+// `secret:"rotate"` and keep them so that rotated values are picked up.
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	RabbitMQ RabbitMQConfig
+	Server     ServerConfig     `mapstructure:"server"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Redis      RedisConfig      `mapstructure:"redis"`
+	Queue      QueueConfig      `mapstructure:"queue"`
+	RabbitMQ   RabbitMQConfig   `mapstructure:"rabbitmq"`
+	Outbox     OutboxConfig     `mapstructure:"outbox"`
+	Scheduler  SchedulerConfig  `mapstructure:"scheduler"`
+	Worker     WorkerConfig     `mapstructure:"worker"`
+	Completion CompletionConfig `mapstructure:"completion"`
+	Secrets    SecretsConfig    `mapstructure:"secrets"`
+	Cache      CacheConfig      `mapstructure:"cache"`
 }
 
 type ServerConfig struct {
-	Port string `mapstructure:"port"`
-	Env  string `mapstructure:"env"`
+	Port       string `mapstructure:"port" validate:"required,port_number"`
+	Env        string `mapstructure:"env"`
+	AdminToken string `mapstructure:"admin_token" secret:"rotate"`
+	LogLevel   string `mapstructure:"log_level" validate:"oneof=debug info warn error"`
 }
 
 type DatabaseConfig struct {
-	Host                  string              `mapstructure:"host"`
-	Port                  int                 `mapstructure:"port"`
-	Name                  string              `mapstructure:"name"`
-	Username              string              `mapstructure:"username"`
-	Password              string              `mapstructure:"password"`
-	Options               map[string][]string `mapstructure:"options"`
-	MaxIdleConnection     int                 `mapstructure:"max_idle_connection"`
-	MaxOpenConnection     int                 `mapstructure:"max_open_connection"`
-	MaxConnectionLifetime int                 `mapstructure:"max_connection_lifetime"`
-	BatchSize             int                 `mapstructure:"batch_size"`
-	SlowThreshold         int                 `mapstructure:"slow_threshold"`
+	// postgres or sqlite
+	Driver string `mapstructure:"driver" validate:"oneof=postgres sqlite"`
+	// Database file of the sqlite driver
+	Path string `mapstructure:"path" validate:"required_if=Driver sqlite"`
+	// postgres:// URL or key=value DSN; takes precedence over host, port,
+	// name, username and options. A password setting overrides the URL's.
+	URL      string              `mapstructure:"url" secret:"rotate"`
+	Host     string              `mapstructure:"host"`
+	Port     int                 `mapstructure:"port" validate:"max=65535"`
+	Name     string              `mapstructure:"name"`
+	Username string              `mapstructure:"username"`
+	Password string              `mapstructure:"password" secret:"rotate"`
+	Options  map[string][]string `mapstructure:"options"`
+	// Client TLS of the primary and replica connections; replaces sslmode
+	TLS                   TLSConfig `mapstructure:"tls"`
+	MaxIdleConnection     int       `mapstructure:"max_idle_connection" validate:"min=0"`
+	MaxOpenConnection     int       `mapstructure:"max_open_connection" validate:"min=0"`
+	MaxConnectionLifetime int       `mapstructure:"max_connection_lifetime" validate:"min=0"`
+	BatchSize             int       `mapstructure:"batch_size"`
+	SlowThreshold         int       `mapstructure:"slow_threshold"`
+	// Apply pending migrations when `serve` starts instead of refusing to start
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+	// Seconds a single repository read or write may take; 0 disables
+	ReadTimeout  int `mapstructure:"read_timeout" validate:"min=0"`
+	WriteTimeout int `mapstructure:"write_timeout" validate:"min=0"`
+	// DSNs of read replicas that serve todo lists and lookups; empty reads
+	// from the primary
+	Replicas []string `mapstructure:"replicas" validate:"dive,required" secret:"rotate"`
+	// Seconds between health checks that take replicas out of and back into
+	// rotation
+	ReplicaCheckInterval int `mapstructure:"replica_check_interval" validate:"min=0"`
 }
 
 type RedisConfig struct {
-	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
-	Password string `mapstructure:"password"`
-	DB       int    `mapstructure:"db"`
+	Mode              string    `mapstructure:"mode" validate:"omitempty,oneof=single sentinel cluster"`
+	Host              string    `mapstructure:"host" validate:"required_without=Addrs"`
+	Port              int       `mapstructure:"port" validate:"required_without=Addrs,max=65535"`
+	Addrs             []string  `mapstructure:"addrs" validate:"dive,hostname_port"`
+	MasterName        string    `mapstructure:"master_name" validate:"required_if=Mode sentinel"`
+	Username          string    `mapstructure:"username"`
+	Password          string    `mapstructure:"password" secret:"rotate"`
+	SentinelUsername  string    `mapstructure:"sentinel_username"`
+	SentinelPassword  string    `mapstructure:"sentinel_password" secret:"rotate"`
+	DB                int       `mapstructure:"db" validate:"min=0"`
+	PoolSize          int       `mapstructure:"pool_size" validate:"min=0"`
+	MinIdleConns      int       `mapstructure:"min_idle_conns" validate:"min=0"`
+	MaxIdleConns      int       `mapstructure:"max_idle_conns" validate:"min=0"`
+	DialTimeout       int       `mapstructure:"dial_timeout" validate:"min=0"`
+	ReadTimeout       int       `mapstructure:"read_timeout" validate:"min=0"`
+	WriteTimeout      int       `mapstructure:"write_timeout" validate:"min=0"`
+	FailureThreshold  int       `mapstructure:"failure_threshold" validate:"min=0"`
+	ReconnectInterval int       `mapstructure:"reconnect_interval" validate:"min=0"`
+	TLS               TLSConfig `mapstructure:"tls"`
+}
+
+const (
+	QueueDriverRabbitMQ = "rabbitmq"
+	// QueueDriverMemory keeps messages in process; only useful when the API
+	// and the worker run together in `all` mode
+	QueueDriverMemory = "memory"
+)
+
+const (
+	// CompletionAsync leaves completing todos to the worker
+	CompletionAsync = "async"
+	// CompletionSync completes todos in the API request
+	CompletionSync = "sync"
+	// CompletionAsyncFallback completes todos in the API request only while
+	// the broker is unreachable
+	CompletionAsyncFallback = "async_fallback"
+)
+
+// ServerWriteTimeout bounds how long the API may take to write a response.
+const ServerWriteTimeout = 10 * time.Second
+
+type CompletionConfig struct {
+	Strategy string `mapstructure:"strategy" validate:"oneof=async sync async_fallback"`
+	// Seconds a `?wait=true` completion waits for the worker; must stay
+	// below ServerWriteTimeout so the API can still answer 202 in time
+	WaitTimeout int `mapstructure:"wait_timeout" validate:"min=1"`
+}
+
+type CacheConfig struct {
+	// Seconds the todo list stays cached in Redis
+	TodosTTL int `mapstructure:"todos_ttl" validate:"min=1"`
+}
+
+type QueueConfig struct {
+	Driver string `mapstructure:"driver" validate:"oneof=rabbitmq memory"`
 }
 
 type RabbitMQConfig struct {
-	Host            string `mapstructure:"host"`
-	Port            int    `mapstructure:"port"`
-	Username        string `mapstructure:"user"`
-	Password        string `mapstructure:"password"`
-	QueueName       string `mapstructure:"queue_name"`
-	ExchangeName    string `mapstructure:"exchange_name"`
-	ExchangeType    string `mapstructure:"exchange_type"`
+	// amqp:// or amqps:// URL; takes precedence over host, port, user,
+	// password and vhost
+	URL      string `mapstructure:"url" secret:"rotate"`
+	Host     string `mapstructure:"host" validate:"required_without=URL"`
+	Port     int    `mapstructure:"port" validate:"required_without=URL,max=65535"`
+	Username string `mapstructure:"user"`
+	Password string `mapstructure:"password" secret:"rotate"`
+	VHost    string `mapstructure:"vhost"`
+	// Connects with amqps when enabled
+	TLS TLSConfig `mapstructure:"tls"`
+	// Permits a plain amqp connection when server.env is not local
+	AllowPlaintext  bool   `mapstructure:"allow_plaintext"`
+	QueueName       string `mapstructure:"queue_name" validate:"required_without=Bindings"`
+	ExchangeName    string `mapstructure:"exchange_name" validate:"required"`
+	ExchangeType    string `mapstructure:"exchange_type" validate:"oneof=direct topic fanout headers"`
 	RoutingKey      string `mapstructure:"routing_key"`
-	PrefetchCount   int    `mapstructure:"prefetch_count"`
-	WorkerPoolCount int    `mapstructure:"worker_pool_count"`
+	PrefetchCount   int    `mapstructure:"prefetch_count" validate:"min=1"`
+	WorkerPoolCount int    `mapstructure:"worker_pool_count" validate:"min=1"`
+	// Queues consumed by `work`; defaults to queue_name bound to routing_key
+	Bindings []BindingConfig `mapstructure:"bindings" validate:"dive"`
+	// Seconds a single handler may run before its context is cancelled
+	HandlerTimeout int `mapstructure:"handler_timeout" validate:"min=0"`
+	// Retry and dead-lettering; delays are in seconds
+	MaxAttempts        int     `mapstructure:"max_attempts" validate:"min=1"`
+	RetryInitialDelay  int     `mapstructure:"retry_initial_delay" validate:"min=0"`
+	RetryMaxDelay      int     `mapstructure:"retry_max_delay" validate:"gtefield=RetryInitialDelay"`
+	RetryMultiplier    float64 `mapstructure:"retry_multiplier" validate:"gte=1"`
+	DeadLetterExchange string  `mapstructure:"dead_letter_exchange"`
+	DeadLetterQueue    string  `mapstructure:"dead_letter_queue"`
+	// Seconds before the first reconnect attempt, doubling up to 30s
+	ReconnectInterval int `mapstructure:"reconnect_interval" validate:"min=0"`
+	// Publisher channel pool size and confirm timeout in seconds
+	PublisherChannels int `mapstructure:"publisher_channels" validate:"min=0"`
+	PublishTimeout    int `mapstructure:"publish_timeout" validate:"min=0"`
+	// Seconds a processed message ID is remembered to drop redeliveries
+	ProcessedRetention int `mapstructure:"processed_retention" validate:"min=0"`
+	// Delayed messages wait on delay_exchange; set delayed_message_plugin
+	// when the rabbitmq_delayed_message_exchange plugin is enabled
+	DelayExchange        string `mapstructure:"delay_exchange"`
+	DelayedMessagePlugin bool   `mapstructure:"delayed_message_plugin"`
+}
+
+type BindingConfig struct {
+	Queue       string   `mapstructure:"queue" validate:"required"`
+	RoutingKeys []string `mapstructure:"routing_keys" validate:"min=1,dive,required"`
 }
 
+type OutboxConfig struct {
+	// Run the relay inside the work process; disable when running `relay`
+	RelayInWorker bool `mapstructure:"relay_in_worker"`
+	PollInterval  int  `mapstructure:"poll_interval" validate:"min=0"`
+	BatchSize     int  `mapstructure:"batch_size" validate:"min=0"`
+	MaxBackoff    int  `mapstructure:"max_backoff" validate:"min=0"`
+	// Failed publishes after which an event is parked with failed_at set
+	MaxAttempts int `mapstructure:"max_attempts" validate:"min=0"`
+	// Seconds after which a pass stops publishing and releases its locks
+	MaxPassDuration int `mapstructure:"max_pass_duration" validate:"min=0"`
+}
+
+type SchedulerConfig struct {
+	PollInterval int `mapstructure:"poll_interval" validate:"min=0"`
+	BatchSize    int `mapstructure:"batch_size" validate:"min=0"`
+	// Seconds ahead of their due time that messages are handed to the
+	// broker as delayed messages; 0 publishes them only once due. Defaults
+	// to 60 with the delayed message plugin and to 0 without it
+	Horizon    int `mapstructure:"horizon" validate:"min=0"`
+	MaxBackoff int `mapstructure:"max_backoff" validate:"min=0"`
+}
+
+type WorkerConfig struct {
+	// Port of the `work` process's metrics and health server; empty disables it
+	AdminPort string `mapstructure:"admin_port" validate:"omitempty,port_number"`
+	// Seconds between samples of the consumed queues' depth
+	DepthInterval int `mapstructure:"depth_interval" validate:"min=0"`
+	// Seconds the RabbitMQ connection may stay down before the liveness
+	// probe fails and the process is restarted
+	DisconnectGrace int `mapstructure:"disconnect_grace" validate:"min=0"`
+}
+
+// LoadConfig reads config.yaml from path, overrides it with the environment
+// and validates the result. A missing file is not an error; every setting
+// can come from the environment. Secret references are resolved once here
+// so that a missing secret fails at startup.
 func LoadConfig(path string) (*Config, error) {
+	cfg, resolver, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	secretResolver.Store(resolver)
+	if err := setLogLevel(cfg.Server.LogLevel); err != nil {
+		return nil, err
+	}
+	if plaintextRabbitMQ(cfg) {
+		if !cfg.RabbitMQ.AllowPlaintext {
+			return nil, fmt.Errorf("refusing to connect to RabbitMQ without TLS while server.env is %q; "+
+				"set rabbitmq.tls.enabled, use an amqps:// rabbitmq.url or set rabbitmq.allow_plaintext", cfg.Server.Env)
+		}
+		logger.Warn(fmt.Sprintf("RabbitMQ traffic is not encrypted although server.env is %q", cfg.Server.Env))
+	}
+
+	return cfg, nil
+}
+
+// loadConfig is LoadConfig without installing the secret resolver and log
+// level, so that a reload can decide what to apply.
+func loadConfig(path string) (*Config, *secrets.Resolver, error) {
+	v, _, err := newViper(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	resolver, err := newSecretResolver(cfg.Secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := expandSecrets(context.Background(), resolver, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return &cfg, resolver, nil
+}
+
+// newViper returns the viper instance behind LoadConfig and the environment
+// variable bound to each key. Every variable also has a _FILE variant naming
+// a file to read the value from, such as a Docker or Kubernetes secret
+// mount; it takes precedence over the variable itself. String settings keep
+// it as a ${secret:file:...} reference so that rotated files are picked up;
+// numbers, booleans and lists are read once here.
+func newViper(path string) (*viper.Viper, map[string]string, error) {
 	v := viper.New()
+	envs := make(map[string]string)
+	strs := stringSettings()
+	var fileErr error
+	bindEnv := func(key string, env ...string) {
+		_ = v.BindEnv(append([]string{key}, env...)...)
+		if len(env) == 0 {
+			env = []string{"APP_" + strings.ToUpper(key)}
+		}
+		envs[key] = env[0]
+		file := os.Getenv(env[0] + "_FILE")
+		if file == "" {
+			return
+		}
+		envs[key] = env[0] + "_FILE"
+		if strs[key] {
+			v.Set(key, secrets.Ref(secrets.ProviderFile, file))
+			return
+		}
+		value, err := secrets.FileProvider{}.Secret(context.Background(), file)
+		if err != nil {
+			if fileErr == nil {
+				fileErr = fmt.Errorf("%s_FILE: %w", env[0], err)
+			}
+			return
+		}
+		v.Set(key, value)
+	}
 
 	v.SetConfigName("config")
 	v.SetConfigType("yaml")
 	v.AddConfigPath(path)
 
 	v.SetDefault("server.port", "8080")
+	v.SetDefault("server.log_level", "info")
+	v.SetDefault("cache.todos_ttl", 30)
+	v.SetDefault("database.driver", DatabaseDriverPostgres)
+	v.SetDefault("database.path", "todoapp.db")
+	v.SetDefault("database.read_timeout", 5)
+	v.SetDefault("database.write_timeout", 10)
+	v.SetDefault("database.replica_check_interval", 10)
+	v.SetDefault("redis.mode", RedisModeSingle)
+	v.SetDefault("queue.driver", QueueDriverRabbitMQ)
+	v.SetDefault("outbox.relay_in_worker", true)
+	v.SetDefault("scheduler.poll_interval", 5)
+	v.SetDefault("completion.strategy", CompletionAsync)
+	v.SetDefault("completion.wait_timeout", 5)
+	v.SetDefault("worker.admin_port", "9090")
+	v.SetDefault("worker.depth_interval", 15)
+	v.SetDefault("worker.disconnect_grace", 120)
+	v.SetDefault("rabbitmq.max_attempts", 5)
+	v.SetDefault("rabbitmq.retry_initial_delay", 1)
+	v.SetDefault("rabbitmq.retry_max_delay", 300)
+	v.SetDefault("rabbitmq.retry_multiplier", 2.0)
+	v.SetDefault("rabbitmq.processed_retention", 7*24*60*60)
+	v.SetDefault("rabbitmq.handler_timeout", 30)
+	v.SetDefault("rabbitmq.vhost", "/")
+	v.SetDefault("secrets.refresh_interval", 60)
+	v.SetDefault("secrets.vault.timeout", 5)
 
 	v.AutomaticEnv()
 	v.SetEnvPrefix("APP")
 
 	// Bind environment variables for server
-	_ = v.BindEnv("server.port")
-	_ = v.BindEnv("server.env", "APP_ENV")
+	bindEnv("server.port")
+	bindEnv("server.env", "APP_ENV")
+	bindEnv("server.admin_token", "APP_ADMIN_TOKEN")
+	bindEnv("server.log_level", "LOG_LEVEL")
+
+	bindEnv("cache.todos_ttl", "CACHE_TODOS_TTL")
 
 	// Bind environment variables for database
-	_ = v.BindEnv("database.host", "DB_HOST")
-	_ = v.BindEnv("database.port", "DB_PORT")
-	_ = v.BindEnv("database.name", "DB_NAME")
-	_ = v.BindEnv("database.username", "DB_USER")
-	_ = v.BindEnv("database.password", "DB_PASSWORD")
+	bindEnv("database.driver", "DB_DRIVER")
+	bindEnv("database.url", "DATABASE_URL")
+	bindEnv("database.path", "DB_PATH")
+	bindEnv("database.host", "DB_HOST")
+	bindEnv("database.port", "DB_PORT")
+	bindEnv("database.name", "DB_NAME")
+	bindEnv("database.username", "DB_USER")
+	bindEnv("database.password", "DB_PASSWORD")
+	bindEnv("database.auto_migrate", "DB_AUTO_MIGRATE")
+	bindEnv("database.read_timeout", "DB_READ_TIMEOUT")
+	bindEnv("database.write_timeout", "DB_WRITE_TIMEOUT")
+	bindEnv("database.replicas", "DB_REPLICAS")
+	bindEnv("database.replica_check_interval", "DB_REPLICA_CHECK_INTERVAL")
+	bindEnv("database.tls.enabled", "DB_TLS_ENABLED")
+	bindEnv("database.tls.ca_file", "DB_TLS_CA_FILE")
+	bindEnv("database.tls.cert_file", "DB_TLS_CERT_FILE")
+	bindEnv("database.tls.key_file", "DB_TLS_KEY_FILE")
+	bindEnv("database.tls.server_name", "DB_TLS_SERVER_NAME")
+	bindEnv("database.tls.insecure_skip_verify", "DB_TLS_INSECURE_SKIP_VERIFY")
 
 	// Bind environment variables for Redis
-	_ = v.BindEnv("redis.host", "REDIS_HOST")
-	_ = v.BindEnv("redis.port", "REDIS_PORT")
-	_ = v.BindEnv("redis.password", "REDIS_PASSWORD")
-	_ = v.BindEnv("redis.db", "REDIS_DB")
+	bindEnv("redis.host", "REDIS_HOST")
+	bindEnv("redis.port", "REDIS_PORT")
+	bindEnv("redis.password", "REDIS_PASSWORD")
+	bindEnv("redis.db", "REDIS_DB")
+	bindEnv("redis.mode", "REDIS_MODE")
+	bindEnv("redis.addrs", "REDIS_ADDRS")
+	bindEnv("redis.master_name", "REDIS_MASTER_NAME")
+	bindEnv("redis.username", "REDIS_USERNAME")
+	bindEnv("redis.sentinel_username", "REDIS_SENTINEL_USERNAME")
+	bindEnv("redis.sentinel_password", "REDIS_SENTINEL_PASSWORD")
+	bindEnv("redis.pool_size", "REDIS_POOL_SIZE")
+	bindEnv("redis.min_idle_conns", "REDIS_MIN_IDLE_CONNS")
+	bindEnv("redis.max_idle_conns", "REDIS_MAX_IDLE_CONNS")
+	bindEnv("redis.tls.enabled", "REDIS_TLS_ENABLED")
+	bindEnv("redis.tls.ca_file", "REDIS_TLS_CA_FILE")
+	bindEnv("redis.tls.cert_file", "REDIS_TLS_CERT_FILE")
+	bindEnv("redis.tls.key_file", "REDIS_TLS_KEY_FILE")
+	bindEnv("redis.tls.server_name", "REDIS_TLS_SERVER_NAME")
 
 	// Bind environment variables for RabbitMQ
-	_ = v.BindEnv("rabbitmq.host", "RABBITMQ_HOST")
-	_ = v.BindEnv("rabbitmq.port", "RABBITMQ_PORT")
-	_ = v.BindEnv("rabbitmq.user", "RABBITMQ_USER")
-	_ = v.BindEnv("rabbitmq.password", "RABBITMQ_PASSWORD")
-	_ = v.BindEnv("rabbitmq.queue_name", "RABBITMQ_QUEUE_NAME")
-	_ = v.BindEnv("rabbitmq.exchange_name", "RABBITMQ_EXCHANGE_NAME")
-	_ = v.BindEnv("rabbitmq.exchange_type", "RABBITMQ_EXCHANGE_TYPE")
-	_ = v.BindEnv("rabbitmq.routing_key", "RABBITMQ_ROUTING_KEY")
-	_ = v.BindEnv("rabbitmq.prefetch_count", "RABBITMQ_PREFETCH_COUNT")
-	_ = v.BindEnv("rabbitmq.worker_pool_count", "RABBITMQ_WORKER_POOL_COUNT")
+	bindEnv("rabbitmq.url", "RABBITMQ_URL")
+	bindEnv("rabbitmq.host", "RABBITMQ_HOST")
+	bindEnv("rabbitmq.port", "RABBITMQ_PORT")
+	bindEnv("rabbitmq.user", "RABBITMQ_USER")
+	bindEnv("rabbitmq.password", "RABBITMQ_PASSWORD")
+	bindEnv("rabbitmq.vhost", "RABBITMQ_VHOST")
+	bindEnv("rabbitmq.tls.enabled", "RABBITMQ_TLS_ENABLED")
+	bindEnv("rabbitmq.tls.ca_file", "RABBITMQ_TLS_CA_FILE")
+	bindEnv("rabbitmq.tls.cert_file", "RABBITMQ_TLS_CERT_FILE")
+	bindEnv("rabbitmq.tls.key_file", "RABBITMQ_TLS_KEY_FILE")
+	bindEnv("rabbitmq.tls.server_name", "RABBITMQ_TLS_SERVER_NAME")
+	bindEnv("rabbitmq.tls.insecure_skip_verify", "RABBITMQ_TLS_INSECURE_SKIP_VERIFY")
+	bindEnv("rabbitmq.allow_plaintext", "RABBITMQ_ALLOW_PLAINTEXT")
+	bindEnv("rabbitmq.queue_name", "RABBITMQ_QUEUE_NAME")
+	bindEnv("rabbitmq.exchange_name", "RABBITMQ_EXCHANGE_NAME")
+	bindEnv("rabbitmq.exchange_type", "RABBITMQ_EXCHANGE_TYPE")
+	bindEnv("rabbitmq.routing_key", "RABBITMQ_ROUTING_KEY")
+	bindEnv("rabbitmq.prefetch_count", "RABBITMQ_PREFETCH_COUNT")
+	bindEnv("rabbitmq.worker_pool_count", "RABBITMQ_WORKER_POOL_COUNT")
+	bindEnv("rabbitmq.max_attempts", "RABBITMQ_MAX_ATTEMPTS")
+	bindEnv("rabbitmq.retry_initial_delay", "RABBITMQ_RETRY_INITIAL_DELAY")
+	bindEnv("rabbitmq.retry_max_delay", "RABBITMQ_RETRY_MAX_DELAY")
+	bindEnv("rabbitmq.retry_multiplier", "RABBITMQ_RETRY_MULTIPLIER")
+	bindEnv("rabbitmq.dead_letter_exchange", "RABBITMQ_DEAD_LETTER_EXCHANGE")
+	bindEnv("rabbitmq.dead_letter_queue", "RABBITMQ_DEAD_LETTER_QUEUE")
+	bindEnv("rabbitmq.reconnect_interval", "RABBITMQ_RECONNECT_INTERVAL")
+	bindEnv("rabbitmq.publisher_channels", "RABBITMQ_PUBLISHER_CHANNELS")
+	bindEnv("rabbitmq.publish_timeout", "RABBITMQ_PUBLISH_TIMEOUT")
+	bindEnv("rabbitmq.processed_retention", "RABBITMQ_PROCESSED_RETENTION")
+	bindEnv("rabbitmq.handler_timeout", "RABBITMQ_HANDLER_TIMEOUT")
+	bindEnv("rabbitmq.delay_exchange", "RABBITMQ_DELAY_EXCHANGE")
+	bindEnv("rabbitmq.delayed_message_plugin", "RABBITMQ_DELAYED_MESSAGE_PLUGIN")
+
+	bindEnv("queue.driver", "QUEUE_DRIVER")
+
+	// Bind environment variables for the outbox relay
+	bindEnv("outbox.relay_in_worker", "OUTBOX_RELAY_IN_WORKER")
+	bindEnv("outbox.poll_interval", "OUTBOX_POLL_INTERVAL")
+	bindEnv("outbox.batch_size", "OUTBOX_BATCH_SIZE")
+	bindEnv("outbox.max_backoff", "OUTBOX_MAX_BACKOFF")
+	bindEnv("outbox.max_attempts", "OUTBOX_MAX_ATTEMPTS")
+	bindEnv("outbox.max_pass_duration", "OUTBOX_MAX_PASS_DURATION")
+
+	// Bind environment variables for the scheduler
+	bindEnv("scheduler.poll_interval", "SCHEDULER_POLL_INTERVAL")
+	bindEnv("scheduler.batch_size", "SCHEDULER_BATCH_SIZE")
+	bindEnv("scheduler.horizon", "SCHEDULER_HORIZON")
+	bindEnv("scheduler.max_backoff", "SCHEDULER_MAX_BACKOFF")
+
+	// Bind environment variables for todo completion
+	bindEnv("completion.strategy", "COMPLETION_STRATEGY")
+	bindEnv("completion.wait_timeout", "COMPLETION_WAIT_TIMEOUT")
+
+	// Bind environment variables for the worker admin server
+	bindEnv("worker.admin_port", "WORKER_ADMIN_PORT")
+	bindEnv("worker.depth_interval", "WORKER_DEPTH_INTERVAL")
+	bindEnv("worker.disconnect_grace", "WORKER_DISCONNECT_GRACE")
+
+	// Bind environment variables for secret providers
+	bindEnv("secrets.refresh_interval", "SECRETS_REFRESH_INTERVAL")
+	bindEnv("secrets.file.dir", "SECRETS_FILE_DIR")
+	bindEnv("secrets.vault.address", "VAULT_ADDR")
+	bindEnv("secrets.vault.token", "VAULT_TOKEN")
+	bindEnv("secrets.vault.timeout", "VAULT_TIMEOUT")
+
+	if fileErr != nil {
+		return nil, nil, fileErr
+	}
 
 	if err := v.ReadInConfig(); err != nil {
-		logger.Warn("Warning: Failed to read config file: %v\n", err)
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		logger.Warn("Warning: No config file found in " + path + ", using defaults and environment variables")
 	}
-	var cfg Config
-	if err := v.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+
+	// Without the plugin all delays share one TTL queue, which only expires
+	// the message at its head, so a short delay queued behind a longer one
+	// is delivered late. Schedule from the database unless told otherwise.
+	if v.GetBool("rabbitmq.delayed_message_plugin") {
+		v.SetDefault("scheduler.horizon", 60)
 	}
+	return v, envs, nil
+}
 
-	return &cfg, nil
+// stringSettings returns the config.yaml keys of the string settings.
+func stringSettings() map[string]bool {
+	keys := make(map[string]bool)
+	var walk func(typ reflect.Type, prefix string)
+	walk = func(typ reflect.Type, prefix string) {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			key := prefix + field.Tag.Get("mapstructure")
+			switch field.Type.Kind() {
+			case reflect.Struct:
+				walk(field.Type, key+".")
+			case reflect.String:
+				keys[key] = true
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	return keys
 }
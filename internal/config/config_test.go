@@ -0,0 +1,374 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/secrets"
+	"github.com/sirupsen/logrus"
+)
+
+const testConfigYAML = `
+server:
+  env: local
+database:
+  host: localhost
+  port: 5432
+  name: todoapp
+  username: root
+  password: secret
+redis:
+  host: localhost
+  port: 6379
+rabbitmq:
+  host: localhost
+  port: 5672
+  password: guest
+  queue_name: todo_queue
+  exchange_name: todo_exchange
+  exchange_type: topic
+  prefetch_count: 1
+  worker_pool_count: 1
+`
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestLoadConfig_Validation(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantProblems []string
+	}{
+		{name: "valid"},
+		{
+			name:         "zero prefetch count",
+			env:          map[string]string{"RABBITMQ_PREFETCH_COUNT": "0"},
+			wantProblems: []string{"rabbitmq.prefetch_count: must be at least 1, got 0"},
+		},
+		{
+			name: "every problem is reported",
+			env: map[string]string{
+				"RABBITMQ_EXCHANGE_TYPE": "fanin",
+				"DB_DRIVER":              "mysql",
+				"COMPLETION_STRATEGY":    "later",
+				"APP_SERVER.PORT":        "http",
+			},
+			wantProblems: []string{
+				`server.port: must be a port number between 1 and 65535, got "http"`,
+				`database.driver: must be one of postgres, sqlite, got "mysql"`,
+				`rabbitmq.exchange_type: must be one of direct, topic, fanout, headers, got "fanin"`,
+				`completion.strategy: must be one of async, sync, async_fallback, got "later"`,
+			},
+		},
+		{
+			name:         "wait timeout must be set",
+			env:          map[string]string{"COMPLETION_WAIT_TIMEOUT": "0"},
+			wantProblems: []string{"completion.wait_timeout: must be at least 1, got 0"},
+		},
+		{
+			name:         "wait timeout must fit in the write timeout",
+			env:          map[string]string{"COMPLETION_WAIT_TIMEOUT": "10"},
+			wantProblems: []string{"completion.wait_timeout: must be less than the API write timeout of 10 seconds, got 10"},
+		},
+		{
+			name: "sqlite needs no database server",
+			env:  map[string]string{"DB_DRIVER": DatabaseDriverSQLite, "DB_HOST": "", "DB_PORT": "0"},
+		},
+		{
+			name:         "sentinel mode needs a master name",
+			env:          map[string]string{"REDIS_MODE": RedisModeSentinel, "REDIS_ADDRS": "s1:26379,s2"},
+			wantProblems: []string{"redis.addrs[1]: must be a host:port address", "redis.master_name: is required when mode is sentinel"},
+		},
+	}
+
+	dir := writeTestConfig(t, testConfigYAML)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := LoadConfig(dir)
+			if len(tt.wantProblems) == 0 {
+				if err != nil {
+					t.Fatalf("LoadConfig() error = %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("LoadConfig() error = %v, want a ValidationError", err)
+			}
+			if len(validationErr.Problems) != len(tt.wantProblems) {
+				t.Fatalf("LoadConfig() problems = %q, want %q", validationErr.Problems, tt.wantProblems)
+			}
+			for i, want := range tt.wantProblems {
+				if !strings.HasPrefix(validationErr.Problems[i], want) {
+					t.Errorf("problem %d = %q, want %q", i, validationErr.Problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfig_PlaintextRabbitMQ(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{name: "local development", env: map[string]string{"APP_ENV": "local"}},
+		{name: "production without tls", env: map[string]string{"APP_ENV": "production"}, wantErr: true},
+		{name: "production allowing plaintext", env: map[string]string{"APP_ENV": "production", "RABBITMQ_ALLOW_PLAINTEXT": "true"}},
+		{name: "production with tls", env: map[string]string{"APP_ENV": "production", "RABBITMQ_TLS_ENABLED": "true"}},
+	}
+
+	dir := writeTestConfig(t, testConfigYAML)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if _, err := LoadConfig(dir); (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_InvalidFile(t *testing.T) {
+	dir := writeTestConfig(t, "rabbitmq: [unclosed")
+	if _, err := LoadConfig(dir); err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+		t.Errorf("LoadConfig() error = %v, want the file's parse error", err)
+	}
+}
+
+func TestLoadConfig_SchedulerHorizon(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra string
+		want  int
+	}{
+		{name: "without the delay plugin", want: 0},
+		{name: "with the delay plugin", extra: "  delayed_message_plugin: true\n", want: 60},
+		{name: "explicit horizon", extra: "scheduler:\n  horizon: 30\n", want: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, _, err := loadConfig(writeTestConfig(t, testConfigYAML+tt.extra))
+			if err != nil {
+				t.Fatalf("loadConfig() error = %v", err)
+			}
+			if cfg.Scheduler.Horizon != tt.want {
+				t.Errorf("scheduler.horizon = %d, want %d", cfg.Scheduler.Horizon, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	t.Setenv("RABBITMQ_PREFETCH_COUNT", "4")
+	dir := writeTestConfig(t, testConfigYAML)
+
+	_, settings, err := Inspect(dir)
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	byKey := make(map[string]Setting, len(settings))
+	for _, setting := range settings {
+		byKey[setting.Key] = setting
+	}
+
+	tests := []struct {
+		key        string
+		wantValue  string
+		wantSource string
+	}{
+		{key: "rabbitmq.prefetch_count", wantValue: "4", wantSource: SourceEnv},
+		{key: "rabbitmq.queue_name", wantValue: "todo_queue", wantSource: SourceFile},
+		{key: "server.port", wantValue: "8080", wantSource: SourceDefault},
+		{key: "database.password", wantValue: Redacted, wantSource: SourceFile},
+		{key: "redis.password", wantValue: "", wantSource: SourceDefault},
+	}
+	for _, tt := range tests {
+		got, ok := byKey[tt.key]
+		if !ok {
+			t.Errorf("Inspect() has no %s", tt.key)
+			continue
+		}
+		if got.Value != tt.wantValue || got.Source != tt.wantSource {
+			t.Errorf("Inspect() %s = %q from %s, want %q from %s", tt.key, got.Value, got.Source, tt.wantValue, tt.wantSource)
+		}
+	}
+}
+
+func TestLoadConfig_Secrets(t *testing.T) {
+	secretsDir := t.TempDir()
+	writeSecret := func(name, value string) string {
+		t.Helper()
+		path := filepath.Join(secretsDir, name)
+		if err := os.WriteFile(path, []byte(value+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	t.Setenv("DB_PASSWORD_FILE", writeSecret("db_password", "from-file"))
+	t.Setenv("RABBITMQ_USER_FILE", writeSecret("rabbitmq_user", "todo"))
+	t.Setenv("RABBITMQ_PREFETCH_COUNT_FILE", writeSecret("prefetch_count", "3"))
+	t.Setenv("SECRETS_FILE_DIR", secretsDir)
+	t.Setenv("SECRETS_REFRESH_INTERVAL", "0")
+	t.Setenv("REDIS_PASSWORD", "${secret:file:redis_password}")
+	writeSecret("redis_password", "before")
+	dir := writeTestConfig(t, testConfigYAML)
+
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.RabbitMQ.Username != "todo" {
+		t.Errorf("rabbitmq.user = %q, want it read from RABBITMQ_USER_FILE", cfg.RabbitMQ.Username)
+	}
+	if cfg.RabbitMQ.PrefetchCount != 3 {
+		t.Errorf("rabbitmq.prefetch_count = %d, want it read from RABBITMQ_PREFETCH_COUNT_FILE", cfg.RabbitMQ.PrefetchCount)
+	}
+	if want := "${secret:file:" + filepath.Join(secretsDir, "db_password") + "}"; cfg.Database.Password != want {
+		t.Errorf("database.password = %q, want the reference %q kept for rotation", cfg.Database.Password, want)
+	}
+	if got, _ := ExpandSecret(context.Background(), cfg.Database.Password); got != "from-file" {
+		t.Errorf("ExpandSecret(database.password) = %q, want from-file", got)
+	}
+
+	opts, err := getRedisConfig(cfg.Redis)
+	if err != nil {
+		t.Fatalf("getRedisConfig() error = %v", err)
+	}
+	writeSecret("redis_password", "after")
+	if _, password := opts.CredentialsProvider(); password != "after" {
+		t.Errorf("redis CredentialsProvider() password = %q, want the rotated value", password)
+	}
+
+	t.Setenv("DB_PASSWORD_FILE", filepath.Join(secretsDir, "missing"))
+	if _, err := LoadConfig(dir); err == nil || !strings.Contains(err.Error(), "database.password") {
+		t.Errorf("LoadConfig() with a missing secret error = %v, want it to name database.password", err)
+	}
+
+	t.Setenv("DB_PASSWORD_FILE", writeSecret("db_password", "from-file"))
+	t.Setenv("RABBITMQ_PREFETCH_COUNT_FILE", filepath.Join(secretsDir, "missing"))
+	if _, err := LoadConfig(dir); err == nil || !strings.Contains(err.Error(), "RABBITMQ_PREFETCH_COUNT_FILE") {
+		t.Errorf("LoadConfig() with a missing setting file error = %v, want it to name RABBITMQ_PREFETCH_COUNT_FILE", err)
+	}
+}
+
+func TestNewSecretResolver_VaultTokenRotation(t *testing.T) {
+	validToken := "first"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != validToken {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"db_password":"s3cret"},"metadata":{"version":1}}}`))
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "vault_token")
+	writeToken := func(token string) {
+		t.Helper()
+		if err := os.WriteFile(tokenFile, []byte(token+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeToken("first")
+
+	resolver, err := newSecretResolver(SecretsConfig{Vault: VaultSecretsConfig{
+		Address: server.URL,
+		Token:   secrets.Ref(secrets.ProviderFile, tokenFile),
+	}})
+	if err != nil {
+		t.Fatalf("newSecretResolver() error = %v", err)
+	}
+	ref := secrets.Ref(secrets.ProviderVault, "secret/data/todoapp#db_password")
+
+	for _, token := range []string{"first", "rotated"} {
+		validToken = token
+		writeToken(token)
+		if got, err := resolver.Expand(context.Background(), ref); err != nil || got != "s3cret" {
+			t.Errorf("Expand() with vault token %q = %q, %v", token, got, err)
+		}
+	}
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	dir := writeTestConfig(t, testConfigYAML)
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	defer logger.SetLogLevel(logrus.InfoLevel)
+
+	watcher := NewWatcher(dir, cfg)
+	var reloaded []*Config
+	watcher.OnChange(func(cfg *Config) { reloaded = append(reloaded, cfg) })
+
+	rewrite := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rewrite(strings.Replace(testConfigYAML, "host: localhost\n  port: 5432", "host: db.internal\n  port: 5432", 1))
+	if err := watcher.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if len(reloaded) != 0 {
+		t.Fatalf("Reload() of a restart-only setting called OnChange %d times", len(reloaded))
+	}
+
+	updated := strings.ReplaceAll(testConfigYAML, "worker_pool_count: 1", "worker_pool_count: 4")
+	rewrite(strings.Replace(updated, "env: local", "env: local\n  log_level: debug", 1) + `
+cache:
+  todos_ttl: 120
+`)
+	if err := watcher.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if len(reloaded) != 1 {
+		t.Fatalf("Reload() called OnChange %d times, want 1", len(reloaded))
+	}
+	got := reloaded[0]
+	if got.RabbitMQ.WorkerPoolCount != 4 || got.Cache.TodosTTL != 120 || got.Server.LogLevel != "debug" {
+		t.Errorf("OnChange() got worker_pool_count %d, todos_ttl %d, log_level %q, want 4, 120 and debug",
+			got.RabbitMQ.WorkerPoolCount, got.Cache.TodosTTL, got.Server.LogLevel)
+	}
+	if got.Database.Host != "localhost" {
+		t.Errorf("OnChange() got database.host %q, want the restart-only change left out", got.Database.Host)
+	}
+	if level := logger.DefaultLogger().GetLevel(); level != logrus.DebugLevel {
+		t.Errorf("log level = %s, want debug", level)
+	}
+
+	rewrite(strings.ReplaceAll(testConfigYAML, "worker_pool_count: 1", "worker_pool_count: 0"))
+	if err := watcher.Reload(); err == nil {
+		t.Error("Reload() of an invalid config should fail")
+	}
+	if len(reloaded) != 1 {
+		t.Errorf("Reload() of an invalid config called OnChange")
+	}
+}
@@ -1,17 +1,141 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/secrets"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"time"
+)
+
+const (
+	DatabaseDriverPostgres = "postgres"
+	// DatabaseDriverSQLite stores everything in one file through a pure-Go
+	// driver, for local development and embedded use. Transactions take the
+	// database write lock up front in place of row locks.
+	DatabaseDriverSQLite = "sqlite"
 )
 
 func ConnectDatabase(dbConfig DatabaseConfig) (*gorm.DB, error) {
-	dns := buildDSN(dbConfig)
+	dialector, err := openDialector(dbConfig)
+	if err != nil {
+		return nil, err
+	}
+	db, err := open(dialector, dbConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if databaseDriver(dbConfig) == DatabaseDriverSQLite {
+		logger.Info("Connected to SQLite database " + dbConfig.Path)
+	} else {
+		logger.Info("Connected to PostgreSQL database")
+	}
+	return db, nil
+}
+
+// ConnectReplicas opens one pool per configured read replica, sized like the
+// primary's.
+func ConnectReplicas(dbConfig DatabaseConfig) ([]*gorm.DB, error) {
+	if len(dbConfig.Replicas) > 0 && databaseDriver(dbConfig) != DatabaseDriverPostgres {
+		return nil, fmt.Errorf("read replicas require the %s driver", DatabaseDriverPostgres)
+	}
+
+	replicas := make([]*gorm.DB, 0, len(dbConfig.Replicas))
+	for i, dsn := range dbConfig.Replicas {
+		dialector, err := postgresDialector(dsn, "", dbConfig.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+		db, err := open(dialector, dbConfig)
+		if err != nil {
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+		replicas = append(replicas, db)
+	}
+
+	if len(replicas) > 0 {
+		logger.Info(fmt.Sprintf("Connected to %d PostgreSQL read replica(s)", len(replicas)))
+	}
+	return replicas, nil
+}
+
+func openDialector(dbConfig DatabaseConfig) (gorm.Dialector, error) {
+	switch databaseDriver(dbConfig) {
+	case DatabaseDriverPostgres:
+		return postgresDialector(postgresDSN(dbConfig), dbConfig.Password, dbConfig.TLS)
+	case DatabaseDriverSQLite:
+		return sqlite.Open(buildSQLiteDSN(dbConfig)), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", dbConfig.Driver)
+	}
+}
+
+// postgresDialector opens dsn through pgx, with password, if set, in place
+// of the DSN's. Secret references in both are resolved again before every
+// new connection, so a rotated password is used once the pool replaces its
+// connections, without a restart. An enabled TLS section replaces sslmode.
+func postgresDialector(dsn, password string, tlsSettings TLSConfig) (gorm.Dialector, error) {
+	connConfig, err := parsePostgresConfig(context.Background(), dsn, password)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := buildTLSConfig(tlsSettings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure database TLS: %w", err)
+	}
+	if tlsConfig != nil {
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = connConfig.Host
+		}
+		connConfig.TLSConfig = tlsConfig
+		connConfig.Fallbacks = nil
+	}
+
+	var options []stdlib.OptionOpenDB
+	if secrets.HasReference(dsn) || secrets.HasReference(password) {
+		options = append(options, stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+			fresh, err := parsePostgresConfig(ctx, dsn, password)
+			if err != nil {
+				return err
+			}
+			cc.User = fresh.User
+			cc.Password = fresh.Password
+			return nil
+		}))
+	}
+	return postgres.New(postgres.Config{Conn: stdlib.OpenDB(*connConfig, options...)}), nil
+}
+
+func parsePostgresConfig(ctx context.Context, dsn, password string) (*pgx.ConnConfig, error) {
+	expanded, err := ExpandSecret(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	connConfig, err := pgx.ParseConfig(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database DSN: %w", err)
+	}
+	if password != "" {
+		if connConfig.Password, err = ExpandSecret(ctx, password); err != nil {
+			return nil, err
+		}
+	}
+	return connConfig, nil
+}
 
-	db, err := gorm.Open(postgres.Open(dns), &gorm.Config{})
+func open(dialector gorm.Dialector, dbConfig DatabaseConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -23,25 +147,61 @@ func ConnectDatabase(dbConfig DatabaseConfig) (*gorm.DB, error) {
 	sqlDB.SetMaxIdleConns(dbConfig.MaxIdleConnection)
 	sqlDB.SetMaxOpenConns(dbConfig.MaxOpenConnection)
 	sqlDB.SetConnMaxLifetime(time.Duration(dbConfig.MaxConnectionLifetime) * time.Second)
-
-	logger.Info("Connected to PostgreSQL database")
 	return db, nil
 }
 
+func databaseDriver(dbConfig DatabaseConfig) string {
+	if dbConfig.Driver == "" {
+		return DatabaseDriverPostgres
+	}
+	return dbConfig.Driver
+}
+
+// postgresDSN returns url, or a DSN built from the connection settings. The
+// password is left out; postgresDialector sets it.
+func postgresDSN(dbConfig DatabaseConfig) string {
+	if dbConfig.URL != "" {
+		return dbConfig.URL
+	}
+	return buildDSN(dbConfig)
+}
+
 func buildDSN(dbConfig DatabaseConfig) string {
-	options := ""
-	for key, values := range dbConfig.Options {
-		for _, value := range values {
-			options += fmt.Sprintf("%s=%s ", key, value)
+	settings := []string{
+		"host=" + dsnValue(dbConfig.Host),
+		"user=" + dsnValue(dbConfig.Username),
+		"dbname=" + dsnValue(dbConfig.Name),
+		"port=" + strconv.Itoa(dbConfig.Port),
+	}
+	keys := make([]string, 0, len(dbConfig.Options))
+	for key := range dbConfig.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range dbConfig.Options[key] {
+			settings = append(settings, key+"="+dsnValue(value))
 		}
 	}
+	return strings.Join(settings, " ")
+}
+
+// dsnValue quotes value for a key=value DSN the way libpq expects when it is
+// empty or contains spaces, quotes or backslashes.
+func dsnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " '\\") {
+		return value
+	}
+	return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(value) + "'"
+}
+
+// buildSQLiteDSN waits for the write lock instead of failing with
+// SQLITE_BUSY, and begins every transaction IMMEDIATE so that reads meant to
+// lock rows (FOR UPDATE, which SQLite ignores) already hold the lock.
+func buildSQLiteDSN(dbConfig DatabaseConfig) string {
 	return fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%d %s",
-		dbConfig.Host,
-		dbConfig.Username,
-		dbConfig.Password,
-		dbConfig.Name,
-		dbConfig.Port,
-		options,
+		"file:%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate",
+		dbConfig.Path,
+		max(dbConfig.WriteTimeout, 5)*1000,
 	)
 }
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+)
+
+// Redacted replaces secret values in Inspect's output.
+const Redacted = "[redacted]"
+
+// Setting is one effective config value and where it came from.
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+	// Env is the environment variable that overrides Key, if any
+	Env string
+}
+
+// Inspect loads the config like LoadConfig and lists every setting, sorted
+// by key, with secrets redacted. The config is returned unvalidated so the
+// caller can report both.
+func Inspect(path string) (*Config, []Setting, error) {
+	v, envs, err := newViper(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	settings := make([]Setting, 0, len(keys))
+	for _, key := range keys {
+		setting := Setting{Key: key, Source: SourceDefault, Env: envs[key]}
+		if value := v.Get(key); value != nil {
+			setting.Value = fmt.Sprint(value)
+		}
+		if value, ok := os.LookupEnv(setting.Env); ok && value != "" {
+			setting.Source = SourceEnv
+		} else if v.InConfig(key) {
+			setting.Source = SourceFile
+		}
+		if isSecret(key) && setting.Value != "" && setting.Value != "[]" {
+			setting.Value = Redacted
+		}
+		settings = append(settings, setting)
+	}
+	return &cfg, settings, nil
+}
+
+// isSecret reports whether key holds a credential. Connection URLs and
+// replica DSNs may embed passwords.
+func isSecret(key string) bool {
+	name := key[strings.LastIndex(key, ".")+1:]
+	return strings.Contains(name, "password") ||
+		strings.Contains(name, "token") ||
+		strings.Contains(name, "secret") ||
+		name == "url" ||
+		key == "database.replicas"
+}
@@ -1,50 +1,333 @@
 package config
 
 import (
-	"crypto/tls"
+	"context"
 	"fmt"
 	"log"
+	"math"
+	"net/url"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// Headers set on messages that are retried or dead-lettered
+const (
+	HeaderAttempts        = "x-attempts"
+	HeaderFailureReason   = "x-failure-reason"
+	HeaderPermanent       = "x-permanent-failure"
+	HeaderOriginalRouting = "x-original-routing-key"
+	HeaderFailedAt        = "x-failed-at"
+)
+
+// Queue owns the RabbitMQ connection. Callers open channels through Channel
+// instead of holding the connection, so they pick up a new connection after
+// the manager in queue_connection.go has reconnected.
 type Queue struct {
-	Conn         *amqp.Connection
-	ExchangeName string
-	QueueName    string
-	RoutingKey   string
+	ExchangeName       string
+	QueueName          string
+	RoutingKey         string
+	PrefetchCount      int
+	WorkerPoolCount    int
+	DeadLetterExchange string
+	DeadLetterQueue    string
+	Retry              RetryPolicy
+	PublisherChannels  int
+	PublishTimeout     time.Duration
+	// How long processed message IDs are kept for deduplication
+	ProcessedRetention time.Duration
+	HandlerTimeout     time.Duration
+	// Bindings lists every consumed queue; the first one is QueueName
+	Bindings []Binding
+	// Delayed messages are published to DelayExchange. Without the plugin
+	// they wait in DelayQueue until their per-message TTL expires and are
+	// dead-lettered to the main exchange under their own routing key.
+	DelayExchange string
+	DelayQueue    string
+	DelayPlugin   bool
+
+	config RabbitMQConfig
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	connected chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Binding is a work queue and the routing keys bound to it on the exchange.
+type Binding struct {
+	Queue       string
+	RoutingKeys []string
+}
+
+// RetryPolicy describes how often and how late a failed message is retried.
+// Attempt n (1-based) is followed by a delay of InitialDelay*Multiplier^(n-1),
+// capped at MaxDelay, until MaxAttempts is reached.
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+}
+
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := time.Duration(float64(p.InitialDelay) * math.Pow(multiplier, float64(attempt-1)))
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// RetryQueueName is the delay queue a message from queueName waits in after
+// its n-th failed attempt.
+func RetryQueueName(queueName string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", queueName, attempt)
+}
+
+func newBindings(config RabbitMQConfig) []Binding {
+	if len(config.Bindings) == 0 {
+		return []Binding{{Queue: config.QueueName, RoutingKeys: []string{config.RoutingKey}}}
+	}
+	bindings := make([]Binding, 0, len(config.Bindings))
+	for _, b := range config.Bindings {
+		bindings = append(bindings, Binding{Queue: b.Queue, RoutingKeys: b.RoutingKeys})
+	}
+	return bindings
+}
+
+func newRetryPolicy(config RabbitMQConfig) RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts:  config.MaxAttempts,
+		InitialDelay: time.Duration(config.RetryInitialDelay) * time.Second,
+		MaxDelay:     time.Duration(config.RetryMaxDelay) * time.Second,
+		Multiplier:   config.RetryMultiplier,
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = time.Second
+	}
+	return policy
 }
 
-func SetupRabbitMQConnection(config RabbitMQConfig, serverConfig ServerConfig) (*Queue, error) {
-	var conn *amqp.Connection
-	var err error
+// NewQueue describes the topology without connecting to RabbitMQ.
+func NewQueue(config RabbitMQConfig) *Queue {
+	queue := &Queue{
+		ExchangeName:       config.ExchangeName,
+		QueueName:          config.QueueName,
+		RoutingKey:         config.RoutingKey,
+		PrefetchCount:      config.PrefetchCount,
+		WorkerPoolCount:    config.WorkerPoolCount,
+		DeadLetterExchange: config.DeadLetterExchange,
+		DeadLetterQueue:    config.DeadLetterQueue,
+		Retry:              newRetryPolicy(config),
+		PublisherChannels:  config.PublisherChannels,
+		PublishTimeout:     time.Duration(config.PublishTimeout) * time.Second,
+		ProcessedRetention: time.Duration(config.ProcessedRetention) * time.Second,
+		HandlerTimeout:     time.Duration(config.HandlerTimeout) * time.Second,
+		Bindings:           newBindings(config),
+		DelayExchange:      config.DelayExchange,
+		DelayPlugin:        config.DelayedMessagePlugin,
+		config:             config,
+		done:               make(chan struct{}),
+		connected:          make(chan struct{}),
+	}
+	if queue.QueueName == "" {
+		queue.QueueName = queue.Bindings[0].Queue
+	}
+	if queue.DelayExchange == "" {
+		queue.DelayExchange = config.ExchangeName + ".delay"
+	}
+	queue.DelayQueue = queue.DelayExchange
+	if queue.DeadLetterExchange == "" {
+		queue.DeadLetterExchange = config.ExchangeName + ".dlx"
+	}
+	if queue.DeadLetterQueue == "" {
+		queue.DeadLetterQueue = queue.QueueName + ".dlq"
+	}
+	return queue
+}
+
+func SetupRabbitMQConnection(config RabbitMQConfig) (*Queue, error) {
+	queue := NewQueue(config)
+
+	conn, err := queue.connect()
+	if err != nil {
+		return nil, err
+	}
+	queue.setConnection(conn)
+	go queue.watch(conn)
+
+	log.Printf("RabbitMQ connection established and queue configured successfully")
+	return queue, nil
+}
+
+// dialRabbitMQ runs on every (re)connect and resolves the credentials again,
+// so a rotated password is used after the next connection loss.
+func dialRabbitMQ(config RabbitMQConfig) (*amqp.Connection, error) {
+	uri, err := rabbitMQURI(config)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := buildTLSConfig(config.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure rabbitmq TLS: %w", err)
+	}
 
-	if serverConfig.Env == "local" {
-		connectionUrl := fmt.Sprintf("amqp://%s:%s@%s:%d/", config.Username, config.Password, config.Host, config.Port)
-		conn, err = amqp.Dial(connectionUrl)
+	// Without a TLS section amqps:// URLs use the system roots, or the
+	// certfile, keyfile and cacertfile URL parameters
+	return amqp.DialConfig(uri.String(), amqp.Config{
+		Vhost:           uri.Vhost,
+		TLSClientConfig: tlsConfig,
+		Locale:          "en_US",
+	})
+}
+
+// rabbitMQURI parses rabbitmq.url, or builds the URI from the host, port,
+// credentials and vhost settings when it is empty. An enabled TLS section
+// switches the scheme to amqps, and an amqp url without a port to the amqps
+// port.
+func rabbitMQURI(config RabbitMQConfig) (amqp.URI, error) {
+	var uri amqp.URI
+	defaultPort := false
+	if config.URL != "" {
+		raw, err := ExpandSecret(context.Background(), config.URL)
+		if err != nil {
+			return amqp.URI{}, err
+		}
+		if uri, err = amqp.ParseURI(raw); err != nil {
+			return amqp.URI{}, fmt.Errorf("failed to parse rabbitmq url: %w", err)
+		}
+		// ParseURI fills in the default port of the scheme
+		if parsed, err := url.Parse(raw); err == nil && parsed.Port() == "" {
+			defaultPort = true
+		}
 	} else {
-		connectionUrl := fmt.Sprintf("amqps://%s:%s@%s:%d/", config.Username, config.Password, config.Host, config.Port)
+		password, err := ExpandSecret(context.Background(), config.Password)
+		if err != nil {
+			return amqp.URI{}, err
+		}
+		uri = amqp.URI{
+			Scheme:   "amqp",
+			Host:     config.Host,
+			Port:     config.Port,
+			Username: config.Username,
+			Password: password,
+			Vhost:    config.VHost,
+		}
+		if uri.Vhost == "" {
+			uri.Vhost = "/"
+		}
+	}
 
-		tlsConfig := &tls.Config{
-			MinVersion: tls.VersionTLS12,
+	if config.TLS.Enabled {
+		if uri.Scheme == "amqp" && defaultPort {
+			uri.Port = 5671
 		}
+		uri.Scheme = "amqps"
+	}
+	return uri, nil
+}
 
-		conn, err = amqp.DialTLS(connectionUrl, tlsConfig)
+// plaintextRabbitMQ reports whether cfg connects to RabbitMQ without TLS
+// outside local development, which LoadConfig refuses unless
+// rabbitmq.allow_plaintext is set. Earlier releases switched to amqps
+// whenever server.env was not local; TLS now only follows the rabbitmq
+// settings.
+func plaintextRabbitMQ(cfg *Config) bool {
+	if cfg.Server.Env == "local" || cfg.Queue.Driver == QueueDriverMemory || cfg.RabbitMQ.TLS.Enabled {
+		return false
 	}
+	uri, err := rabbitMQURI(cfg.RabbitMQ)
+	return err == nil && uri.Scheme == "amqp"
+}
+
+// declareTopology is idempotent and runs on every (re)connect.
+func declareTopology(ch *amqp.Channel, queue *Queue) error {
+	err := ch.ExchangeDeclare(
+		queue.ExchangeName,
+		queue.config.ExchangeType,
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+		return fmt.Errorf("failed to declare exchange: %w", err)
 	}
 
-	ch, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to open a channel: %w", err)
+	for _, binding := range queue.Bindings {
+		_, err = ch.QueueDeclare(
+			binding.Queue,
+			true,
+			false,
+			false,
+			false,
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare queue %s: %w", binding.Queue, err)
+		}
+
+		for _, key := range binding.RoutingKeys {
+			err = ch.QueueBind(
+				binding.Queue,
+				key,
+				queue.ExchangeName,
+				false,
+				nil,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to bind queue %s to %s: %w", binding.Queue, key, err)
+			}
+		}
+	}
+
+	if err := declareDelayTopology(ch, queue); err != nil {
+		return err
+	}
+	return declareRetryTopology(ch, queue)
+}
+
+// declareDelayTopology binds the delay exchange to the main exchange. The
+// plugin holds messages in the exchange itself; otherwise a fanout exchange
+// feeds a consumer-less queue that dead-letters expired messages onwards.
+// Per-message TTLs only expire at the head of that queue, so the scheduler
+// keeps delays short.
+func declareDelayTopology(ch *amqp.Channel, queue *Queue) error {
+	if queue.DelayPlugin {
+		err := ch.ExchangeDeclare(
+			queue.DelayExchange,
+			"x-delayed-message",
+			true,
+			false,
+			false,
+			false,
+			amqp.Table{"x-delayed-type": amqp.ExchangeFanout},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare delay exchange: %w", err)
+		}
+		if err := ch.ExchangeBind(queue.ExchangeName, "", queue.DelayExchange, false, nil); err != nil {
+			return fmt.Errorf("failed to bind delay exchange: %w", err)
+		}
+		return nil
 	}
-	defer ch.Close()
 
-	err = ch.ExchangeDeclare(
-		config.ExchangeName,
-		config.ExchangeType,
+	err := ch.ExchangeDeclare(
+		queue.DelayExchange,
+		amqp.ExchangeFanout,
 		true,
 		false,
 		false,
@@ -52,50 +335,86 @@ func SetupRabbitMQConnection(config RabbitMQConfig, serverConfig ServerConfig) (
 		nil,
 	)
 	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to declare exchange: %w", err)
+		return fmt.Errorf("failed to declare delay exchange: %w", err)
 	}
 
 	_, err = ch.QueueDeclare(
-		config.QueueName,
+		queue.DelayQueue,
 		true,
 		false,
 		false,
 		false,
-		nil,
+		amqp.Table{"x-dead-letter-exchange": queue.ExchangeName},
 	)
 	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to declare queue: %w", err)
+		return fmt.Errorf("failed to declare delay queue: %w", err)
 	}
 
-	err = ch.QueueBind(
-		config.QueueName,
-		config.RoutingKey,
-		config.ExchangeName,
+	if err := ch.QueueBind(queue.DelayQueue, "", queue.DelayExchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind delay queue: %w", err)
+	}
+	return nil
+}
+
+// declareRetryTopology declares one delay queue per retry attempt and the
+// dead-letter exchange and queue. A delay queue has no consumers: messages
+// expire after its TTL and are dead-lettered through the default exchange
+// straight back onto their work queue, so retries skip other bindings.
+func declareRetryTopology(ch *amqp.Channel, queue *Queue) error {
+	for _, binding := range queue.Bindings {
+		for attempt := 1; attempt < queue.Retry.MaxAttempts; attempt++ {
+			_, err := ch.QueueDeclare(
+				RetryQueueName(binding.Queue, attempt),
+				true,
+				false,
+				false,
+				false,
+				amqp.Table{
+					"x-message-ttl":             queue.Retry.Delay(attempt).Milliseconds(),
+					"x-dead-letter-exchange":    "",
+					"x-dead-letter-routing-key": binding.Queue,
+				},
+			)
+			if err != nil {
+				return fmt.Errorf("failed to declare retry queue: %w", err)
+			}
+		}
+	}
+
+	err := ch.ExchangeDeclare(
+		queue.DeadLetterExchange,
+		amqp.ExchangeFanout,
+		true,
+		false,
+		false,
 		false,
 		nil,
 	)
 	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to bind queue: %w", err)
+		return fmt.Errorf("failed to declare dead-letter exchange: %w", err)
 	}
 
-	err = ch.Qos(
-		config.PrefetchCount,
-		0,
+	_, err = ch.QueueDeclare(
+		queue.DeadLetterQueue,
+		true,
+		false,
+		false,
 		false,
+		nil,
 	)
-
 	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to set QoS: %w", err)
+		return fmt.Errorf("failed to declare dead-letter queue: %w", err)
 	}
 
-	log.Printf("RabbitMQ connection established and queue configured successfully")
-	return &Queue{Conn: conn,
-		ExchangeName: config.ExchangeName,
-		QueueName:    config.QueueName,
-		RoutingKey:   config.RoutingKey,
-	}, nil
+	err = ch.QueueBind(
+		queue.DeadLetterQueue,
+		"",
+		queue.DeadLetterExchange,
+		false,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to bind dead-letter queue: %w", err)
+	}
+	return nil
 }
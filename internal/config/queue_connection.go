@@ -0,0 +1,201 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	defaultQueueReconnectInterval   = 1 * time.Second
+	defaultQueueMaxReconnectBackoff = 30 * time.Second
+)
+
+var ErrQueueDisconnected = errors.New("rabbitmq connection is down")
+
+var (
+	QueueConnected = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "rabbitmq_connected",
+			Help: "1 while the RabbitMQ connection is open",
+		})
+
+	QueueReconnects = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "rabbitmq_reconnects_total",
+			Help: "Total number of successful RabbitMQ reconnects",
+		})
+)
+
+func init() {
+	prometheus.MustRegister(QueueConnected, QueueReconnects)
+}
+
+// Channel opens a channel on the current connection. It fails fast with
+// ErrQueueDisconnected while the manager is reconnecting.
+func (q *Queue) Channel() (*amqp.Channel, error) {
+	q.mu.RLock()
+	conn := q.conn
+	q.mu.RUnlock()
+
+	if conn == nil || conn.IsClosed() {
+		return nil, ErrQueueDisconnected
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a channel: %w", err)
+	}
+	return ch, nil
+}
+
+func (q *Queue) Connected() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.conn != nil && !q.conn.IsClosed()
+}
+
+// Ping is the health check probe for the connection.
+func (q *Queue) Ping(ctx context.Context) error {
+	if !q.Connected() {
+		return ErrQueueDisconnected
+	}
+	return nil
+}
+
+// WaitConnected blocks until a connection is available or ctx is done.
+func (q *Queue) WaitConnected(ctx context.Context) error {
+	q.mu.RLock()
+	connected := q.connected
+	q.mu.RUnlock()
+
+	select {
+	case <-connected:
+		return nil
+	case <-q.done:
+		return ErrQueueDisconnected
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) Close() error {
+	q.closeOnce.Do(func() { close(q.done) })
+
+	q.mu.Lock()
+	conn := q.conn
+	q.conn = nil
+	q.mu.Unlock()
+	QueueConnected.Set(0)
+
+	if conn == nil || conn.IsClosed() {
+		return nil
+	}
+	return conn.Close()
+}
+
+// connect dials and declares the topology on the new connection.
+func (q *Queue) connect() (*amqp.Connection, error) {
+	conn, err := dialRabbitMQ(q.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open a channel: %w", err)
+	}
+	defer ch.Close()
+
+	if err := declareTopology(ch, q); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (q *Queue) setConnection(conn *amqp.Connection) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.conn = conn
+	select {
+	case <-q.connected:
+	default:
+		close(q.connected)
+	}
+	QueueConnected.Set(1)
+}
+
+func (q *Queue) setDisconnected() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.conn = nil
+	select {
+	case <-q.connected:
+		q.connected = make(chan struct{})
+	default:
+	}
+	QueueConnected.Set(0)
+}
+
+// watch reconnects whenever the broker closes the connection, until Close is
+// called.
+func (q *Queue) watch(conn *amqp.Connection) {
+	for {
+		closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+		select {
+		case <-q.done:
+			return
+		case amqpErr := <-closed:
+			select {
+			case <-q.done:
+				return
+			default:
+			}
+			logger.Warn("RabbitMQ connection lost: ", amqpErr)
+			q.setDisconnected()
+		}
+
+		conn = q.reconnect()
+		if conn == nil {
+			return
+		}
+	}
+}
+
+func (q *Queue) reconnect() *amqp.Connection {
+	backoff := defaultQueueReconnectInterval
+	if q.config.ReconnectInterval > 0 {
+		backoff = time.Duration(q.config.ReconnectInterval) * time.Second
+	}
+
+	for {
+		select {
+		case <-q.done:
+			return nil
+		case <-time.After(backoff):
+		}
+
+		conn, err := q.connect()
+		if err == nil {
+			q.setConnection(conn)
+			QueueReconnects.Inc()
+			logger.Info("Reconnected to RabbitMQ")
+			return conn
+		}
+
+		logger.Warn("RabbitMQ reconnect failed, retrying in ", backoff*2, ": ", err)
+		backoff *= 2
+		if backoff > defaultQueueMaxReconnectBackoff {
+			backoff = defaultQueueMaxReconnectBackoff
+		}
+	}
+}
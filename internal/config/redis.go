@@ -2,48 +2,271 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
 	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/go-redis/v9"
-	"time"
 )
 
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+const (
+	defaultCacheFailureThreshold    = 3
+	defaultCacheReconnectInterval   = 1 * time.Second
+	defaultCacheMaxReconnectBackoff = 30 * time.Second
+)
+
+var CacheDegraded = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "cache_degraded",
+		Help: "1 when Redis is unreachable and reads are served from the database",
+	})
+
+func init() {
+	prometheus.MustRegister(CacheDegraded)
+}
+
+// Cache wraps the Redis client with a circuit breaker. While the breaker is
+// open every call fails fast with domain.ErrCacheUnavailable and a background
+// loop keeps pinging Redis until it answers again.
 type Cache struct {
-	client *redis.Client
+	client            redis.UniversalClient
+	breaker           *circuitBreaker
+	reconnectInterval time.Duration
+	maxBackoff        time.Duration
+
+	mu           sync.Mutex
+	reconnecting bool
+	stop         chan struct{}
+	stopOnce     sync.Once
 }
 
-func ConnectRedis(config RedisConfig) *Cache {
-	client := redis.NewClient(getRedisConfig(config))
+// ConnectRedis only fails on invalid configuration. An unreachable server
+// starts the cache in degraded mode instead of stopping the process.
+func ConnectRedis(config RedisConfig) (*Cache, error) {
+	client, err := newRedisClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &Cache{
+		client:            client,
+		reconnectInterval: defaultCacheReconnectInterval,
+		maxBackoff:        defaultCacheMaxReconnectBackoff,
+		stop:              make(chan struct{}),
+	}
+	if config.ReconnectInterval > 0 {
+		cache.reconnectInterval = time.Duration(config.ReconnectInterval) * time.Second
+	}
+	threshold := config.FailureThreshold
+	if threshold <= 0 {
+		threshold = defaultCacheFailureThreshold
+	}
+	cache.breaker = newCircuitBreaker(threshold, cache.onBreakerChange)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
-		logger.Fatal("Failed to connect to Redis:", err)
+		logger.Warn("Redis is unreachable, starting in degraded mode: ", err)
+		cache.breaker.Trip()
+		return cache, nil
 	}
 
-	return &Cache{
-		client: client,
+	logger.Info("Connected to Redis in ", redisMode(config), " mode")
+	return cache, nil
+}
+
+func newRedisClient(config RedisConfig) (redis.UniversalClient, error) {
+	opts, err := getRedisConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch redisMode(config) {
+	case RedisModeSingle:
+		return redis.NewClient(opts.Simple()), nil
+	case RedisModeSentinel:
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("redis master_name is required in sentinel mode")
+		}
+		return redis.NewFailoverClient(opts.Failover()), nil
+	case RedisModeCluster:
+		return redis.NewClusterClient(opts.Cluster()), nil
+	default:
+		return nil, fmt.Errorf("unsupported redis mode: %s", config.Mode)
 	}
 }
 
-func getRedisConfig(config RedisConfig) *redis.Options {
-	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
-	return &redis.Options{
-		Addr:     addr,
-		Password: config.Password,
-		DB:       config.DB,
+func getRedisConfig(config RedisConfig) (*redis.UniversalOptions, error) {
+	tlsConfig, err := buildTLSConfig(config.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure redis TLS: %w", err)
+	}
+
+	addrs := config.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{fmt.Sprintf("%s:%d", config.Host, config.Port)}
 	}
+
+	password, err := ExpandSecret(context.Background(), config.Password)
+	if err != nil {
+		return nil, err
+	}
+	sentinelPassword, err := ExpandSecret(context.Background(), config.SentinelPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            addrs,
+		MasterName:       config.MasterName,
+		Username:         config.Username,
+		Password:         password,
+		SentinelUsername: config.SentinelUsername,
+		SentinelPassword: sentinelPassword,
+		DB:               config.DB,
+		PoolSize:         config.PoolSize,
+		MinIdleConns:     config.MinIdleConns,
+		MaxIdleConns:     config.MaxIdleConns,
+		DialTimeout:      time.Duration(config.DialTimeout) * time.Second,
+		ReadTimeout:      time.Duration(config.ReadTimeout) * time.Second,
+		WriteTimeout:     time.Duration(config.WriteTimeout) * time.Second,
+		TLSConfig:        tlsConfig,
+	}
+	// Sentinel credentials are only sent when discovering the master, so
+	// rotation is limited to the server password
+	if config.Password != password {
+		opts.CredentialsProvider = func() (string, string) {
+			rotated, err := ExpandSecret(context.Background(), config.Password)
+			if err != nil {
+				logger.Error("Failed to resolve the Redis password: ", err)
+				return config.Username, password
+			}
+			return config.Username, rotated
+		}
+	}
+	return opts, nil
+}
+
+func redisMode(config RedisConfig) string {
+	if config.Mode == "" {
+		return RedisModeSingle
+	}
+	return config.Mode
+}
+
+func (c *Cache) onBreakerChange(open bool) {
+	if !open {
+		CacheDegraded.Set(0)
+		logger.Info("Redis connection restored, leaving degraded mode")
+		return
+	}
+
+	CacheDegraded.Set(1)
+	logger.Warn("Redis circuit breaker opened, serving from the database")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reconnecting {
+		return
+	}
+	c.reconnecting = true
+	go c.reconnect()
+}
+
+func (c *Cache) reconnect() {
+	backoff := c.reconnectInterval
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-time.After(backoff):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := c.client.Ping(ctx).Err()
+		cancel()
+		if err == nil {
+			// Clear the flag before closing the breaker so a failure right
+			// after recovery can start a new loop.
+			c.mu.Lock()
+			c.reconnecting = false
+			c.mu.Unlock()
+			c.breaker.Success()
+			return
+		}
+
+		logger.Debug("Redis reconnect attempt failed: ", err)
+		backoff *= 2
+		if backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
+
+// record feeds the result of a Redis call into the breaker. Cache misses
+// count as successful calls.
+func (c *Cache) record(err error) error {
+	if err == nil || errors.Is(err, redis.Nil) {
+		c.breaker.Success()
+		return err
+	}
+	// A caller giving up says nothing about the health of Redis.
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	c.breaker.Failure()
+	return err
+}
+
+// Degraded reports whether the cache is currently bypassed.
+func (c *Cache) Degraded() bool {
+	return !c.breaker.Allow()
+}
+
+func (c *Cache) Ping(ctx context.Context) error {
+	if c.Degraded() {
+		return domain.ErrCacheUnavailable
+	}
+	return c.record(c.client.Ping(ctx).Err())
 }
 
 func (c *Cache) Close() error {
+	c.stopOnce.Do(func() { close(c.stop) })
 	return c.client.Close()
 }
 
 func (c *Cache) Get(ctx context.Context, key string) (string, error) {
-	return c.client.Get(ctx, key).Result()
+	if c.Degraded() {
+		return "", domain.ErrCacheUnavailable
+	}
+	value, err := c.client.Get(ctx, key).Result()
+	if err = c.record(err); errors.Is(err, redis.Nil) {
+		return "", domain.ErrCacheMiss
+	}
+	return value, err
 }
 
 func (c *Cache) Set(ctx context.Context, key string, value string, expiration time.Duration) error {
-	return c.client.Set(ctx, key, value, expiration).Err()
+	if c.Degraded() {
+		return domain.ErrCacheUnavailable
+	}
+	return c.record(c.client.Set(ctx, key, value, expiration).Err())
+}
+
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	if c.Degraded() {
+		return domain.ErrCacheUnavailable
+	}
+	return c.record(c.client.Del(ctx, key).Err())
 }
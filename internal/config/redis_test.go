@@ -0,0 +1,95 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNewRedisClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RedisConfig
+		wantErr bool
+		check   func(client redis.UniversalClient) bool
+	}{
+		{
+			name:   "default mode is single",
+			config: RedisConfig{Host: "localhost", Port: 6379},
+			check: func(client redis.UniversalClient) bool {
+				c, ok := client.(*redis.Client)
+				return ok && c.Options().Addr == "localhost:6379"
+			},
+		},
+		{
+			name:   "sentinel mode",
+			config: RedisConfig{Mode: RedisModeSentinel, MasterName: "mymaster", Addrs: []string{"s1:26379", "s2:26379"}},
+			check: func(client redis.UniversalClient) bool {
+				_, ok := client.(*redis.Client)
+				return ok
+			},
+		},
+		{
+			name:    "sentinel mode without master name",
+			config:  RedisConfig{Mode: RedisModeSentinel, Addrs: []string{"s1:26379"}},
+			wantErr: true,
+		},
+		{
+			name:   "cluster mode with a single seed",
+			config: RedisConfig{Mode: RedisModeCluster, Addrs: []string{"c1:6379"}},
+			check: func(client redis.UniversalClient) bool {
+				_, ok := client.(*redis.ClusterClient)
+				return ok
+			},
+		},
+		{
+			name:    "unknown mode",
+			config:  RedisConfig{Mode: "ring"},
+			wantErr: true,
+		},
+		{
+			name:    "missing CA file",
+			config:  RedisConfig{Host: "localhost", Port: 6379, TLS: TLSConfig{Enabled: true, CAFile: "/does/not/exist.pem"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newRedisClient(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newRedisClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			defer client.Close()
+
+			if !tt.check(client) {
+				t.Errorf("newRedisClient() returned unexpected client %T", client)
+			}
+		})
+	}
+}
+
+func TestConnectRedis_Unreachable(t *testing.T) {
+	cache, err := ConnectRedis(RedisConfig{Host: "127.0.0.1", Port: 1, DialTimeout: 1, ReconnectInterval: 60})
+	if err != nil {
+		t.Fatalf("ConnectRedis() error = %v, want degraded cache", err)
+	}
+	defer cache.Close()
+
+	if !cache.Degraded() {
+		t.Fatal("ConnectRedis() cache should start degraded")
+	}
+	if got := testutil.ToFloat64(CacheDegraded); got != 1 {
+		t.Errorf("cache_degraded = %v, want 1", got)
+	}
+	if _, err := cache.Get(context.Background(), "todos"); !errors.Is(err, domain.ErrCacheUnavailable) {
+		t.Errorf("Get() error = %v, want %v", err, domain.ErrCacheUnavailable)
+	}
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// withReloadable returns a copy of current with the settings that can
+// change at runtime taken from next. Changes to any other setting are logged
+// and only take effect after a restart.
+func withReloadable(current, next *Config) *Config {
+	cfg := *current
+	cfg.Server.LogLevel = next.Server.LogLevel
+	cfg.Cache.TodosTTL = next.Cache.TodosTTL
+	cfg.RabbitMQ.WorkerPoolCount = next.RabbitMQ.WorkerPoolCount
+	cfg.RabbitMQ.PrefetchCount = next.RabbitMQ.PrefetchCount
+	return &cfg
+}
+
+// Watcher reloads the config when config.yaml changes or the process
+// receives SIGHUP. The runtime settings of a valid new config are applied to
+// the log level and handed to every OnChange callback; an invalid one is
+// logged and ignored.
+type Watcher struct {
+	path string
+
+	mu        sync.Mutex
+	current   *Config
+	callbacks []func(cfg *Config)
+}
+
+func NewWatcher(path string, cfg *Config) *Watcher {
+	return &Watcher{path: path, current: cfg}
+}
+
+// OnChange registers fn to be called with the config after every reload
+// that changed a runtime setting. Callbacks run one at a time and should
+// apply the settings they own atomically.
+func (w *Watcher) OnChange(fn func(cfg *Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callbacks = append(w.callbacks, fn)
+}
+
+// Watch reloads on file changes and SIGHUP until ctx is cancelled.
+func (w *Watcher) Watch(ctx context.Context) {
+	v, _, err := newViper(w.path)
+	if err != nil {
+		logger.Error("Failed to watch the config file: ", err)
+	} else if v.ConfigFileUsed() != "" {
+		v.OnConfigChange(func(event fsnotify.Event) {
+			if ctx.Err() == nil {
+				_ = w.Reload()
+			}
+		})
+		v.WatchConfig()
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			logger.Info("Received SIGHUP, reloading config")
+			_ = w.Reload()
+		}
+	}
+}
+
+// Reload loads the config again and applies its runtime settings.
+func (w *Watcher) Reload() error {
+	next, _, err := loadConfig(w.path)
+	if err != nil {
+		logger.Error("Failed to reload config, keeping the current one: ", err)
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	applied := withReloadable(w.current, next)
+	reloadable := diffConfig(w.current, applied)
+	changed := false
+	for _, change := range sortedChanges(diffConfig(w.current, next)) {
+		if _, ok := reloadable[change.key]; ok {
+			logger.Info(fmt.Sprintf("Config reloaded: %s changed from %s to %s", change.key, change.from, change.to))
+			changed = true
+		} else {
+			logger.Warn(fmt.Sprintf("Config %s changed from %s to %s but needs a restart to take effect", change.key, change.from, change.to))
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	w.current = applied
+	if err := setLogLevel(applied.Server.LogLevel); err != nil {
+		logger.Error(err)
+	}
+	for _, fn := range w.callbacks {
+		fn(applied)
+	}
+	return nil
+}
+
+func setLogLevel(level string) error {
+	if level == "" {
+		return nil
+	}
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("failed to set log level: %w", err)
+	}
+	logger.SetLogLevel(parsed)
+	return nil
+}
+
+type configChange struct {
+	key      string
+	from, to string
+}
+
+// diffConfig lists the settings that differ between a and b by key, with
+// secrets redacted.
+func diffConfig(a, b *Config) map[string]configChange {
+	before, after := flattenConfig(a), flattenConfig(b)
+	changes := make(map[string]configChange)
+	for key, value := range after {
+		if before[key] == value {
+			continue
+		}
+		change := configChange{key: key, from: before[key], to: value}
+		if isSecret(key) {
+			change.from, change.to = Redacted, Redacted
+		}
+		changes[key] = change
+	}
+	return changes
+}
+
+// flattenConfig prints every setting of cfg by its config.yaml key.
+func flattenConfig(cfg *Config) map[string]string {
+	values := make(map[string]string)
+	var flatten func(value reflect.Value, prefix string)
+	flatten = func(value reflect.Value, prefix string) {
+		for i := 0; i < value.NumField(); i++ {
+			key := prefix + value.Type().Field(i).Tag.Get("mapstructure")
+			field := value.Field(i)
+			if field.Kind() == reflect.Struct {
+				flatten(field, key+".")
+				continue
+			}
+			values[key] = fmt.Sprint(field.Interface())
+		}
+	}
+	flatten(reflect.ValueOf(cfg).Elem(), "")
+	return values
+}
+
+// sortedChanges orders changes by key for stable logs.
+func sortedChanges(changes map[string]configChange) []configChange {
+	sorted := make([]configChange, 0, len(changes))
+	for _, change := range changes {
+		sorted = append(sorted, change)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].key < sorted[j].key })
+	return sorted
+}
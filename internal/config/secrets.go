@@ -0,0 +1,137 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/internal/secrets"
+)
+
+type SecretsConfig struct {
+	// Seconds a resolved secret is reused before it is read again; rotated
+	// credentials are picked up by the first connection after that
+	RefreshInterval int                `mapstructure:"refresh_interval" validate:"min=0"`
+	File            FileSecretsConfig  `mapstructure:"file"`
+	Vault           VaultSecretsConfig `mapstructure:"vault"`
+}
+
+type FileSecretsConfig struct {
+	// Directory relative ${secret:file:...} paths are read from
+	Dir string `mapstructure:"dir"`
+}
+
+type VaultSecretsConfig struct {
+	// Vault server, e.g. http://127.0.0.1:8200; empty disables ${secret:vault:...}
+	Address string `mapstructure:"address" validate:"omitempty,url"`
+	Token   string `mapstructure:"token" validate:"required_with=Address" secret:"rotate"`
+	// Seconds a single Vault request may take
+	Timeout int `mapstructure:"timeout" validate:"min=0"`
+}
+
+var secretResolver atomic.Pointer[secrets.Resolver]
+
+func init() {
+	resolver := secrets.NewResolver(0)
+	resolver.Register(secrets.ProviderFile, secrets.FileProvider{})
+	secretResolver.Store(resolver)
+}
+
+// ExpandSecret replaces the ${secret:...} references in value through the
+// resolver of the last loaded config. Credentials keep their references in
+// Config so that each new connection expands them again and sees rotated
+// values.
+func ExpandSecret(ctx context.Context, value string) (string, error) {
+	if !secrets.HasReference(value) {
+		return value, nil
+	}
+	return secretResolver.Load().Expand(ctx, value)
+}
+
+func newSecretResolver(config SecretsConfig) (*secrets.Resolver, error) {
+	resolver := secrets.NewResolver(time.Duration(config.RefreshInterval) * time.Second)
+	resolver.Register(secrets.ProviderFile, secrets.FileProvider{Dir: config.File.Dir})
+
+	if config.Vault.Address != "" {
+		// The Vault token itself may come from a file, e.g. VAULT_TOKEN_FILE,
+		// and is read again like any other rotated secret. Its resolver only
+		// knows files, so a token cannot refer back to Vault.
+		tokens := secrets.NewResolver(time.Duration(config.RefreshInterval) * time.Second)
+		tokens.Register(secrets.ProviderFile, secrets.FileProvider{Dir: config.File.Dir})
+		if _, err := tokens.Expand(context.Background(), config.Vault.Token); err != nil {
+			return nil, fmt.Errorf("failed to resolve the vault token: %w", err)
+		}
+		token := func(ctx context.Context) (string, error) {
+			return tokens.Expand(ctx, config.Vault.Token)
+		}
+		timeout := time.Duration(max(config.Vault.Timeout, 1)) * time.Second
+		resolver.Register(secrets.ProviderVault, secrets.NewVaultProvider(config.Vault.Address, token, timeout))
+	}
+	return resolver, nil
+}
+
+// expandSecrets resolves every reference in cfg once, so that a missing
+// secret fails at startup. Fields tagged `secret:"rotate"` keep their
+// references for ExpandSecret; all others are replaced by their values.
+func expandSecrets(ctx context.Context, resolver *secrets.Resolver, cfg *Config) error {
+	return expandStruct(ctx, resolver, reflect.ValueOf(cfg).Elem(), "")
+}
+
+func expandStruct(ctx context.Context, resolver *secrets.Resolver, value reflect.Value, prefix string) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		rotate := field.Tag.Get("secret") == "rotate"
+
+		expand := func(v reflect.Value) error {
+			expanded, err := resolver.Expand(ctx, v.String())
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			if !rotate {
+				v.SetString(expanded)
+			}
+			return nil
+		}
+
+		fieldValue := value.Field(i)
+		switch fieldValue.Kind() {
+		case reflect.Struct:
+			if err := expandStruct(ctx, resolver, fieldValue, key+"."); err != nil {
+				return err
+			}
+		case reflect.String:
+			if err := expand(fieldValue); err != nil {
+				return err
+			}
+		case reflect.Slice:
+			for j := 0; j < fieldValue.Len(); j++ {
+				elem := fieldValue.Index(j)
+				if elem.Kind() == reflect.String {
+					if err := expand(elem); err != nil {
+						return err
+					}
+				} else if elem.Kind() == reflect.Struct {
+					if err := expandStruct(ctx, resolver, elem, fmt.Sprintf("%s[%d].", key, j)); err != nil {
+						return err
+					}
+				}
+			}
+		case reflect.Map:
+			if fieldValue.Type().Elem().Kind() != reflect.Slice || fieldValue.Type().Elem().Elem().Kind() != reflect.String {
+				continue
+			}
+			for _, mapKey := range fieldValue.MapKeys() {
+				values := fieldValue.MapIndex(mapKey)
+				for j := 0; j < values.Len(); j++ {
+					if err := expand(values.Index(j)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
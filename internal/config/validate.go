@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationError lists every invalid setting at once, named by its key in
+// config.yaml, so a bad deployment fails at startup with the whole picture.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+var configValidator = newConfigValidator()
+
+func newConfigValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	// The built-in port rule only accepts integers; ports are strings here
+	_ = v.RegisterValidation("port_number", func(fl validator.FieldLevel) bool {
+		port, err := strconv.Atoi(fl.Field().String())
+		return err == nil && port > 0 && port <= 65535
+	})
+	v.RegisterStructValidation(validateDatabase, DatabaseConfig{})
+	v.RegisterStructValidation(validateCompletion, CompletionConfig{})
+	return v
+}
+
+// validateDatabase requires the connection settings of the postgres driver
+// unless they all come from url.
+func validateDatabase(sl validator.StructLevel) {
+	db := sl.Current().Interface().(DatabaseConfig)
+	if databaseDriver(db) != DatabaseDriverPostgres || db.URL != "" {
+		return
+	}
+	required := []struct {
+		field, key string
+		missing    bool
+	}{
+		{"Host", "host", db.Host == ""},
+		{"Port", "port", db.Port == 0},
+		{"Name", "name", db.Name == ""},
+		{"Username", "username", db.Username == ""},
+	}
+	for _, r := range required {
+		if r.missing {
+			sl.ReportError(sl.Current().FieldByName(r.field).Interface(), r.key, r.field, "required_for_postgres", "")
+		}
+	}
+}
+
+// validateCompletion keeps wait_timeout below ServerWriteTimeout, so a
+// request that stops waiting can still be answered.
+func validateCompletion(sl validator.StructLevel) {
+	completion := sl.Current().Interface().(CompletionConfig)
+	limit := int(ServerWriteTimeout / time.Second)
+	if completion.WaitTimeout >= limit {
+		sl.ReportError(completion.WaitTimeout, "wait_timeout", "WaitTimeout", "below_write_timeout", strconv.Itoa(limit))
+	}
+}
+
+// Validate checks cfg against the validate tags of its sections and returns
+// a *ValidationError describing each failed rule.
+func (cfg *Config) Validate() error {
+	err := configValidator.Struct(cfg)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return fmt.Errorf("failed to validate config: %w", err)
+	}
+	problems := make([]string, 0, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		// The namespace starts with the Config type name
+		_, key, _ := strings.Cut(fe.Namespace(), ".")
+		problems = append(problems, key+": "+problem(fe))
+	}
+	return &ValidationError{Problems: problems}
+}
+
+func problem(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_if":
+		field, value, _ := strings.Cut(fe.Param(), " ")
+		return fmt.Sprintf("is required when %s is %s", snakeCase(field), value)
+	case "required_without":
+		return fmt.Sprintf("is required when %s is empty", snakeCase(fe.Param()))
+	case "required_for_postgres":
+		return "is required by the postgres driver unless url is set"
+	case "required_with":
+		return fmt.Sprintf("is required together with %s", snakeCase(fe.Param()))
+	case "min", "gte":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("needs at least %s entries", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s, got %v", fe.Param(), fe.Value())
+	case "below_write_timeout":
+		return fmt.Sprintf("must be less than the API write timeout of %s seconds, got %v", fe.Param(), fe.Value())
+	case "max":
+		return fmt.Sprintf("must be at most %s, got %v", fe.Param(), fe.Value())
+	case "gtefield":
+		return fmt.Sprintf("must not be less than %s, got %v", snakeCase(fe.Param()), fe.Value())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s, got %q", strings.ReplaceAll(fe.Param(), " ", ", "), fe.Value())
+	case "port_number":
+		return fmt.Sprintf("must be a port number between 1 and 65535, got %q", fe.Value())
+	case "hostname_port":
+		return fmt.Sprintf("must be a host:port address, got %q", fe.Value())
+	default:
+		return fmt.Sprintf("failed the %s check", fe.Tag())
+	}
+}
+
+// snakeCase turns the Go field names used in rule parameters into their
+// config keys.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+var ReplicaHealthy = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "database_replica_healthy",
+		Help: "Whether a read replica is receiving reads (1) or skipped (0)",
+	},
+	[]string{"replica"},
+)
+
+func init() {
+	prometheus.MustRegister(ReplicaHealthy)
+}
+
+const replicaPingTimeout = 2 * time.Second
+
+// Cluster routes reads to healthy read replicas and everything else to the
+// primary. Without replicas every query goes to the primary.
+type Cluster struct {
+	primary  *gorm.DB
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+type replica struct {
+	name    string
+	db      *gorm.DB
+	healthy atomic.Bool
+}
+
+func NewCluster(primary *gorm.DB, replicas ...*gorm.DB) *Cluster {
+	c := &Cluster{primary: primary}
+	for i, db := range replicas {
+		r := &replica{name: strconv.Itoa(i), db: db}
+		r.healthy.Store(true)
+		ReplicaHealthy.WithLabelValues(r.name).Set(1)
+		c.replicas = append(c.replicas, r)
+	}
+	return c
+}
+
+func (c *Cluster) Primary() *gorm.DB {
+	return c.primary
+}
+
+// Read runs fn on a healthy replica, or on the primary when there is none or
+// ctx is pinned to it by an earlier write. A replica that fails for any
+// reason other than a missing record or ctx is marked unhealthy and fn is
+// retried on the primary.
+func (c *Cluster) Read(ctx context.Context, fn func(db *gorm.DB) error) error {
+	r := c.pick(ctx)
+	if r == nil {
+		return fn(c.primary)
+	}
+
+	err := fn(r.db)
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) || ctx.Err() != nil {
+		return err
+	}
+	logger.Warn(fmt.Sprintf("Read replica %s failed, falling back to the primary: %v", r.name, err))
+	c.setHealthy(r, false)
+	return fn(c.primary)
+}
+
+func (c *Cluster) pick(ctx context.Context) *replica {
+	if len(c.replicas) == 0 || wrote(ctx) {
+		return nil
+	}
+	start := c.next.Add(1)
+	for i := range c.replicas {
+		r := c.replicas[(int(start)+i)%len(c.replicas)]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// Monitor pings every replica each interval until ctx is cancelled, taking
+// failing replicas out of rotation and putting recovered ones back. A
+// non-positive interval disables it; failed reads still take replicas out.
+func (c *Cluster) Monitor(ctx context.Context, interval time.Duration) {
+	if len(c.replicas) == 0 || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, r := range c.replicas {
+			c.setHealthy(r, ping(ctx, r.db) == nil)
+		}
+	}
+}
+
+func (c *Cluster) setHealthy(r *replica, healthy bool) {
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		logger.Info("Read replica " + r.name + " is healthy again")
+		ReplicaHealthy.WithLabelValues(r.name).Set(1)
+	} else {
+		logger.Warn("Read replica " + r.name + " is unhealthy, reading from the primary")
+		ReplicaHealthy.WithLabelValues(r.name).Set(0)
+	}
+}
+
+func ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+type sessionKey struct{}
+
+type session struct {
+	wrote atomic.Bool
+}
+
+// WithSession scopes read-your-writes to ctx: once a write has gone through
+// a context derived from it, later reads go to the primary as well.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// MarkWrite pins the session of ctx, if any, to the primary.
+func MarkWrite(ctx context.Context) {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		s.wrote.Store(true)
+	}
+}
+
+func wrote(ctx context.Context) bool {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	return ok && s.wrote.Load()
+}
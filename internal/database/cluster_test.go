@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestCluster_Read(t *testing.T) {
+	primary, replica := &gorm.DB{}, &gorm.DB{}
+	session := WithSession(context.Background())
+	written := WithSession(context.Background())
+	MarkWrite(written)
+
+	tests := []struct {
+		name        string
+		replicas    []*gorm.DB
+		ctx         context.Context
+		replicaErr  error
+		want        []*gorm.DB
+		wantHealthy bool
+	}{
+		{name: "no replicas", ctx: context.Background(), want: []*gorm.DB{primary}},
+		{name: "replica", replicas: []*gorm.DB{replica}, ctx: context.Background(), want: []*gorm.DB{replica}, wantHealthy: true},
+		{name: "session without writes", replicas: []*gorm.DB{replica}, ctx: session, want: []*gorm.DB{replica}, wantHealthy: true},
+		{name: "after a write", replicas: []*gorm.DB{replica}, ctx: written, want: []*gorm.DB{primary}, wantHealthy: true},
+		{name: "record not found", replicas: []*gorm.DB{replica}, ctx: context.Background(), replicaErr: gorm.ErrRecordNotFound, want: []*gorm.DB{replica}, wantHealthy: true},
+		{name: "replica failure", replicas: []*gorm.DB{replica}, ctx: context.Background(), replicaErr: errors.New("connection refused"), want: []*gorm.DB{replica, primary}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := NewCluster(primary, tt.replicas...)
+
+			var got []*gorm.DB
+			_ = cluster.Read(tt.ctx, func(db *gorm.DB) error {
+				got = append(got, db)
+				if db == replica {
+					return tt.replicaErr
+				}
+				return nil
+			})
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Read() used %d connections, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("call %d went to the wrong connection", i)
+				}
+			}
+			if len(tt.replicas) > 0 && cluster.replicas[0].healthy.Load() != tt.wantHealthy {
+				t.Errorf("replica healthy = %v, want %v", !tt.wantHealthy, tt.wantHealthy)
+			}
+		})
+	}
+}
+
+func TestCluster_ReadSkipsUnhealthyReplicas(t *testing.T) {
+	primary, down, up := &gorm.DB{}, &gorm.DB{}, &gorm.DB{}
+	cluster := NewCluster(primary, down, up)
+	cluster.setHealthy(cluster.replicas[0], false)
+
+	for i := 0; i < 4; i++ {
+		_ = cluster.Read(context.Background(), func(db *gorm.DB) error {
+			if db != up {
+				t.Errorf("read %d did not go to the healthy replica", i)
+			}
+			return nil
+		})
+	}
+
+	cluster.setHealthy(cluster.replicas[1], false)
+	_ = cluster.Read(context.Background(), func(db *gorm.DB) error {
+		if db != primary {
+			t.Error("read did not fall back to the primary with every replica down")
+		}
+		return nil
+	})
+}
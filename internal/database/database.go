@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"gorm.io/gorm"
+)
+
+// Timeouts bound a single repository operation. Zero disables the timeout,
+// leaving only the caller's deadline.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+func NewTimeouts(dbConfig config.DatabaseConfig) Timeouts {
+	return Timeouts{
+		Read:  time.Duration(dbConfig.ReadTimeout) * time.Second,
+		Write: time.Duration(dbConfig.WriteTimeout) * time.Second,
+	}
+}
+
+// WithTimeout derives the context for one operation. The caller must call
+// cancel once the operation is done.
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Error maps err from an operation run with ctx to a domain error, keeping
+// the cause in the chain. It needs gorm.Config.TranslateError to recognise
+// constraint violations.
+func Error(ctx context.Context, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("%w: %w", domain.ErrNotFound, err)
+	case errors.Is(err, gorm.ErrDuplicatedKey), errors.Is(err, gorm.ErrForeignKeyViolated):
+		return fmt.Errorf("%w: %w", domain.ErrConflict, err)
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", domain.ErrTimeout, err)
+	default:
+		return err
+	}
+}
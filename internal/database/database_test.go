@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"gorm.io/gorm"
+)
+
+func TestError(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want error
+	}{
+		{name: "nil", ctx: context.Background(), err: nil, want: nil},
+		{name: "not found", ctx: context.Background(), err: gorm.ErrRecordNotFound, want: domain.ErrNotFound},
+		{name: "duplicate key", ctx: context.Background(), err: gorm.ErrDuplicatedKey, want: domain.ErrConflict},
+		{name: "foreign key", ctx: context.Background(), err: gorm.ErrForeignKeyViolated, want: domain.ErrConflict},
+		{name: "driver deadline", ctx: context.Background(), err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: domain.ErrTimeout},
+		{name: "expired context", ctx: expired, err: errors.New("conn closed"), want: domain.ErrTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Error(tt.ctx, tt.err)
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("Error() = %v, want nil", got)
+				}
+				return
+			}
+			if !errors.Is(got, tt.want) || !errors.Is(got, tt.err) {
+				t.Errorf("Error() = %v, want it to wrap %v and %v", got, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), 0)
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("WithTimeout(0) should not set a deadline")
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Error("cancel should cancel the context")
+	}
+
+	ctx, cancel = WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, ok := ctx.Deadline(); !ok {
+		t.Error("WithTimeout(time.Minute) should set a deadline")
+	}
+}
@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	HandledEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "events_handled_total",
+			Help: "Total number of handled messages by event type and outcome",
+		},
+		[]string{"type", "outcome"},
+	)
+
+	HandleDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "event_handle_duration_seconds",
+			Help:    "Duration of message handlers by event type",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"type"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(HandledEvents, HandleDuration)
+}
+
+// Recover turns a panicking handler into a permanent failure, so the message
+// is dead-lettered instead of crashing the worker.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error(fmt.Sprintf("Handler for %s panicked: %v\n%s", msg.Name(), r, debug.Stack()))
+					err = domain.Permanent(fmt.Errorf("handler panicked: %v", r))
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Trace carries the event's correlation ID and tenant into the handler's
+// context so anything it emits stays correlated with the original request.
+func Trace() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			correlationID := msg.Delivery.CorrelationId
+			if msg.Event != nil {
+				if msg.Event.CorrelationID != "" {
+					correlationID = msg.Event.CorrelationID
+				}
+				if msg.Event.Tenant != "" {
+					ctx = utils.WithTenant(ctx, msg.Event.Tenant)
+				}
+			}
+			if correlationID == "" {
+				correlationID = msg.ID()
+			}
+			if correlationID != "" {
+				ctx = utils.WithCorrelationID(ctx, correlationID)
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			start := time.Now()
+			err := next(ctx, msg)
+
+			fields := logrus.Fields{
+				"type":           msg.Name(),
+				"id":             msg.ID(),
+				"routing_key":    msg.RoutingKey,
+				"correlation_id": utils.CorrelationID(ctx),
+				"duration":       time.Since(start).String(),
+			}
+			entry := logger.DefaultLogger().WithFields(fields)
+			if err != nil {
+				entry.WithError(err).Warn("Message handler failed")
+			} else {
+				entry.Debug("Message handled")
+			}
+			return err
+		}
+	}
+}
+
+func Metrics() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			start := time.Now()
+			err := next(ctx, msg)
+
+			outcome := "success"
+			if domain.IsPermanent(err) {
+				outcome = "permanent_failure"
+			} else if err != nil {
+				outcome = "failure"
+			}
+			label := msg.label
+			if label == "" {
+				label = UnknownLabel
+			}
+			HandledEvents.WithLabelValues(label, outcome).Inc()
+			HandleDuration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// Timeout cancels the handler's context after d; zero disables it.
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		if d <= 0 {
+			return next
+		}
+		return func(ctx context.Context, msg *Message) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(ctx, msg)
+		}
+	}
+}
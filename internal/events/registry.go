@@ -0,0 +1,200 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/domain/dto"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Message is a delivery being dispatched. Event is nil when the body is not
+// an event, which only handlers registered for a routing key can accept.
+type Message struct {
+	Delivery   amqp.Delivery
+	Event      *dto.Envelope
+	RoutingKey string
+
+	// label is what the message was dispatched by, for metrics
+	label string
+}
+
+// ID is the event ID, falling back to the AMQP message ID for legacy
+// events.
+func (m *Message) ID() string {
+	if m.Event != nil && m.Event.ID != "" {
+		return m.Event.ID
+	}
+	return m.Delivery.MessageId
+}
+
+// Name labels the message in logs and metrics.
+func (m *Message) Name() string {
+	if m.Event != nil {
+		return m.Event.Type
+	}
+	return m.RoutingKey
+}
+
+// UnknownLabel labels messages no handler is registered for, so metric
+// labels stay bounded by what the registry knows.
+const UnknownLabel = "unknown"
+
+type Handler func(ctx context.Context, msg *Message) error
+
+// Middleware wraps every handler run by a Registry.
+type Middleware func(Handler) Handler
+
+type route struct {
+	pattern string
+	handler Handler
+}
+
+// Registry dispatches deliveries to the handler registered for their event
+// type, or else to the first handler whose routing key pattern matches.
+type Registry struct {
+	mu         sync.RWMutex
+	types      map[string]Handler
+	routes     []route
+	middleware []Middleware
+}
+
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]Handler)}
+}
+
+// Use appends middleware; the first one added is the outermost.
+func (r *Registry) Use(middleware ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, middleware...)
+}
+
+func (r *Registry) Register(eventType string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.types[eventType]; ok {
+		panic(fmt.Sprintf("events: handler for %s registered twice", eventType))
+	}
+	r.types[eventType] = handler
+}
+
+// RegisterRoute handles messages whose routing key matches pattern, using
+// AMQP topic syntax ("*" matches one word, "#" zero or more).
+func (r *Registry) RegisterRoute(pattern string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, route{pattern: pattern, handler: handler})
+}
+
+// Dispatch is the queue handler for a worker pool. Messages nobody handles
+// fail permanently.
+func (r *Registry) Dispatch(ctx context.Context, delivery amqp.Delivery) error {
+	msg := &Message{Delivery: delivery, RoutingKey: routingKey(delivery)}
+	event, decodeErr := dto.DecodeEvent(delivery.Body)
+	if decodeErr == nil {
+		msg.Event = event
+	}
+
+	handler, middleware := r.lookup(msg)
+	if handler == nil {
+		if decodeErr != nil {
+			return domain.Permanent(fmt.Errorf("failed to decode message: %w", decodeErr))
+		}
+		return domain.Permanent(fmt.Errorf("unknown event type: %s", event.Type))
+	}
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler(ctx, msg)
+}
+
+func (r *Registry) lookup(msg *Message) (Handler, []Middleware) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if msg.Event != nil {
+		if handler, ok := r.types[msg.Event.Type]; ok {
+			msg.label = msg.Event.Type
+			return handler, r.middleware
+		}
+	}
+	for _, route := range r.routes {
+		if MatchRoutingKey(route.pattern, msg.RoutingKey) {
+			msg.label = route.pattern
+			return route.handler, r.middleware
+		}
+	}
+	return nil, nil
+}
+
+// Label names a delivery for metrics without decoding its body: its AMQP
+// type when a handler is registered for that event type, else the first
+// route pattern matching its routing key, else UnknownLabel.
+func (r *Registry) Label(delivery amqp.Delivery) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.types[delivery.Type]; ok {
+		return delivery.Type
+	}
+	key := routingKey(delivery)
+	for _, route := range r.routes {
+		if MatchRoutingKey(route.pattern, key) {
+			return route.pattern
+		}
+	}
+	return UnknownLabel
+}
+
+// routingKey is the key the message was first published with. Retries come
+// back through the default exchange under the queue name.
+func routingKey(delivery amqp.Delivery) string {
+	if key, ok := delivery.Headers[config.HeaderOriginalRouting].(string); ok && key != "" {
+		return key
+	}
+	return delivery.RoutingKey
+}
+
+// Typed adapts a handler that takes the decoded data of one data version.
+func Typed[T any](dataVersion string, fn func(ctx context.Context, msg *Message, data T) error) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		if msg.Event == nil {
+			return domain.Permanent(fmt.Errorf("message on %s is not an event", msg.RoutingKey))
+		}
+		var data T
+		if err := msg.Event.DecodeData(dataVersion, &data); err != nil {
+			return domain.Permanent(err)
+		}
+		return fn(ctx, msg, data)
+	}
+}
+
+// MatchRoutingKey reports whether key matches a topic exchange pattern.
+func MatchRoutingKey(pattern, key string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(key, "."))
+}
+
+func matchWords(pattern, key []string) bool {
+	if len(pattern) == 0 {
+		return len(key) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(key); i++ {
+			if matchWords(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(key) > 0 && matchWords(pattern[1:], key[1:])
+	default:
+		return len(key) > 0 && pattern[0] == key[0] && matchWords(pattern[1:], key[1:])
+	}
+}
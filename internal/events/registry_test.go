@@ -0,0 +1,202 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/domain/dto"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func todoCompletedDelivery(t *testing.T, todoID int) amqp.Delivery {
+	t.Helper()
+	envelope, err := dto.NewEnvelope("evt-1", dto.EventTodoCompleted, dto.TodoCompletedDataVersion, "todos/1", dto.TodoCompletedData{TodoID: todoID})
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	body, _ := json.Marshal(envelope)
+	return amqp.Delivery{Body: body, RoutingKey: "todo.notification"}
+}
+
+func TestRegistry_Dispatch(t *testing.T) {
+	var got []string
+	registry := NewRegistry()
+	registry.Register(dto.EventTodoCompleted, Typed(dto.TodoCompletedDataVersion,
+		func(ctx context.Context, msg *Message, data dto.TodoCompletedData) error {
+			got = append(got, "type")
+			if data.TodoID != 7 || msg.ID() != "evt-1" {
+				t.Errorf("handler got todo_id = %d, id = %q", data.TodoID, msg.ID())
+			}
+			return nil
+		}))
+	registry.RegisterRoute("audit.#", func(ctx context.Context, msg *Message) error {
+		got = append(got, "route:"+msg.RoutingKey)
+		return nil
+	})
+
+	tests := []struct {
+		name          string
+		delivery      amqp.Delivery
+		want          string
+		wantPermanent bool
+	}{
+		{
+			name:     "dispatches by event type",
+			delivery: todoCompletedDelivery(t, 7),
+			want:     "type",
+		},
+		{
+			name:     "falls back to the routing key for non-events",
+			delivery: amqp.Delivery{Body: []byte(`plain text`), RoutingKey: "audit.login"},
+			want:     "route:audit.login",
+		},
+		{
+			name: "uses the original routing key of retried messages",
+			delivery: amqp.Delivery{
+				Body:       []byte(`plain text`),
+				RoutingKey: "todo_queue",
+				Headers:    amqp.Table{config.HeaderOriginalRouting: "audit.logout"},
+			},
+			want: "route:audit.logout",
+		},
+		{
+			name:          "unknown event types fail permanently",
+			delivery:      amqp.Delivery{Body: []byte(`{"event":"todo_deleted"}`), RoutingKey: "todo.notification"},
+			wantPermanent: true,
+		},
+		{
+			name:          "undecodable messages without a route fail permanently",
+			delivery:      amqp.Delivery{Body: []byte(`plain text`), RoutingKey: "todo.notification"},
+			wantPermanent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			err := registry.Dispatch(context.Background(), tt.delivery)
+			if tt.wantPermanent {
+				if !domain.IsPermanent(err) {
+					t.Fatalf("Dispatch() error = %v, want a permanent error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Dispatch() error = %v", err)
+			}
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("Dispatch() ran %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Label(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(dto.EventTodoCompleted, func(ctx context.Context, msg *Message) error { return nil })
+	registry.RegisterRoute("audit.#", func(ctx context.Context, msg *Message) error { return nil })
+
+	tests := []struct {
+		name     string
+		delivery amqp.Delivery
+		want     string
+	}{
+		{
+			name:     "registered event type",
+			delivery: amqp.Delivery{Type: dto.EventTodoCompleted, RoutingKey: "todo.notification"},
+			want:     dto.EventTodoCompleted,
+		},
+		{
+			name:     "route pattern for unregistered types",
+			delivery: amqp.Delivery{Type: "audit.login.v1", RoutingKey: "audit.login"},
+			want:     "audit.#",
+		},
+		{
+			name: "route pattern of a retried message",
+			delivery: amqp.Delivery{
+				RoutingKey: "todo_queue",
+				Headers:    amqp.Table{config.HeaderOriginalRouting: "audit.logout"},
+			},
+			want: "audit.#",
+		},
+		{
+			name:     "unknown for anything else",
+			delivery: amqp.Delivery{Type: "todo.deleted.x123", RoutingKey: "todo.notification"},
+			want:     UnknownLabel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := registry.Label(tt.delivery); got != tt.want {
+				t.Errorf("Label() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Middleware(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, msg *Message) error {
+				order = append(order, name)
+				return next(ctx, msg)
+			}
+		}
+	}
+
+	registry := NewRegistry()
+	registry.Use(Recover(), trace("outer"), trace("inner"))
+	registry.Register(dto.EventTodoCompleted, func(ctx context.Context, msg *Message) error {
+		order = append(order, "handler")
+		panic("boom")
+	})
+
+	err := registry.Dispatch(context.Background(), todoCompletedDelivery(t, 7))
+	if !domain.IsPermanent(err) {
+		t.Errorf("Dispatch() error = %v, want a permanent error after a panic", err)
+	}
+	if len(order) != 3 || order[0] != "outer" || order[1] != "inner" || order[2] != "handler" {
+		t.Errorf("middleware ran in order %v", order)
+	}
+}
+
+func TestTyped_VersionMismatch(t *testing.T) {
+	handler := Typed("2", func(ctx context.Context, msg *Message, data dto.TodoCompletedData) error {
+		return errors.New("should not run")
+	})
+
+	event, _ := dto.DecodeEvent(todoCompletedDelivery(t, 7).Body)
+	err := handler(context.Background(), &Message{Event: event})
+	if !domain.IsPermanent(err) {
+		t.Errorf("Typed() error = %v, want a permanent error", err)
+	}
+}
+
+func TestMatchRoutingKey(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"#.notification", "todo.notification", true},
+		{"#.notification", "notification", true},
+		{"#.notification", "todo.created", false},
+		{"todo.*", "todo.created", true},
+		{"todo.*", "todo.created.v2", false},
+		{"todo.#", "todo", true},
+		{"#", "anything.at.all", true},
+		{"todo.created", "todo.created", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchRoutingKey(tt.pattern, tt.key); got != tt.want {
+			t.Errorf("MatchRoutingKey(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDegraded = "degraded"
+)
+
+// Check probes one dependency. A failing non-critical check only adds a
+// warning to the readiness report; a failing critical check marks the
+// process as not ready. A failing liveness check also fails the liveness
+// probe, asking the orchestrator to restart the process.
+type Check struct {
+	Name     string
+	Critical bool
+	Liveness bool
+	Probe    func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status   string                 `json:"status"`
+	Checks   map[string]CheckResult `json:"checks"`
+	Warnings []string               `json:"warnings,omitempty"`
+}
+
+type Registry struct {
+	mu      sync.RWMutex
+	checks  []Check
+	timeout time.Duration
+}
+
+func New(checks ...Check) *Registry {
+	return &Registry{
+		checks:  checks,
+		timeout: 2 * time.Second,
+	}
+}
+
+func (r *Registry) Register(check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check)
+}
+
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]Check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(checks))}
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		err := check.Probe(checkCtx)
+		cancel()
+
+		if err == nil {
+			report.Checks[check.Name] = CheckResult{Status: StatusUp}
+			continue
+		}
+
+		if check.Critical {
+			report.Checks[check.Name] = CheckResult{Status: StatusDown, Error: err.Error()}
+			report.Status = StatusDown
+			continue
+		}
+
+		report.Checks[check.Name] = CheckResult{Status: StatusDegraded, Error: err.Error()}
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s is degraded: %v", check.Name, err))
+		if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (r *Registry) Live(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	var checks []Check
+	for _, check := range r.checks {
+		if check.Liveness {
+			checks = append(checks, check)
+		}
+	}
+	r.mu.RUnlock()
+
+	failed := make(map[string]string)
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(req.Context(), r.timeout)
+		if err := check.Probe(checkCtx); err != nil {
+			failed[check.Name] = err.Error()
+		}
+		cancel()
+	}
+	if len(failed) > 0 {
+		utils.WriteError(w, http.StatusServiceUnavailable, "Not alive", failed)
+		return
+	}
+	utils.WriteSuccess(w, http.StatusOK, "Alive", nil)
+}
+
+func (r *Registry) Ready(w http.ResponseWriter, req *http.Request) {
+	report := r.Run(req.Context())
+	if report.Status == StatusDown {
+		utils.WriteError(w, http.StatusServiceUnavailable, "Not ready", report)
+		return
+	}
+	utils.WriteSuccess(w, http.StatusOK, "Ready", report)
+}
+
+// Sustained only fails once probe has been failing for longer than grace,
+// so a liveness check does not restart the process over a blip that its
+// reconnect logic would ride out.
+func Sustained(probe func(ctx context.Context) error, grace time.Duration) func(ctx context.Context) error {
+	var (
+		mu          sync.Mutex
+		failingFrom time.Time
+	)
+	return func(ctx context.Context) error {
+		err := probe(ctx)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err == nil {
+			failingFrom = time.Time{}
+			return nil
+		}
+		if failingFrom.IsZero() {
+			failingFrom = time.Now()
+		}
+		if time.Since(failingFrom) < grace {
+			return nil
+		}
+		return fmt.Errorf("failing for %s: %w", time.Since(failingFrom).Truncate(time.Second), err)
+	}
+}
+
+func DatabaseCheck(db *gorm.DB) Check {
+	return Check{
+		Name:     "database",
+		Critical: true,
+		Probe: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	}
+}
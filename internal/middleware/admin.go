@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/utils"
+)
+
+// AdminAuth only lets through requests carrying the admin bearer token. A
+// ${secret:...} token is resolved per request, so rotating it needs no
+// restart.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			expected, err := config.ExpandSecret(r.Context(), token)
+			if err != nil {
+				logger.Error("Failed to resolve the admin token: ", err)
+				utils.WriteError(w, http.StatusServiceUnavailable, "Admin token unavailable", nil)
+				return
+			}
+			provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if expected == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) != 1 {
+				utils.WriteError(w, http.StatusUnauthorized, "Unauthorized", nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/nayeem-bd/Todo-App/internal/utils"
+)
+
+const (
+	CorrelationIDHeader = "X-Correlation-ID"
+	TenantHeader        = "X-Tenant-ID"
+)
+
+// Correlation stores the caller's correlation ID, or the request ID when
+// none was sent, and tenant in the request context so events emitted while
+// handling the request carry them.
+func Correlation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		correlationID := r.Header.Get(CorrelationIDHeader)
+		if correlationID == "" {
+			correlationID = middleware.GetReqID(ctx)
+		}
+		if correlationID != "" {
+			ctx = utils.WithCorrelationID(ctx, correlationID)
+			w.Header().Set(CorrelationIDHeader, correlationID)
+		}
+
+		if tenant := r.Header.Get(TenantHeader); tenant != "" {
+			ctx = utils.WithTenant(ctx, tenant)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
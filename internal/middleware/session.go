@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/nayeem-bd/Todo-App/internal/database"
+)
+
+// ReadYourWrites scopes a database session to the request, so reads that
+// follow a write in the same request go to the primary instead of a replica
+// that may not have caught up yet.
+func ReadYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(database.WithSession(r.Context())))
+	})
+}
@@ -1,15 +1,486 @@
 package migrations
 
 import (
-	"github.com/nayeem-bd/Todo-App/domain"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/nayeem-bd/Todo-App/internal/logger"
 	"gorm.io/gorm"
 )
 
-func Migrate(db *gorm.DB) {
-	err := db.AutoMigrate(&domain.Todo{})
+// Dir is where `migrate create` writes new migrations, relative to the
+// repository root. They are embedded into the binary at build time.
+const Dir = "internal/migrations/sql"
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var (
+	ErrPending = errors.New("database schema has pending migrations")
+	ErrDirty   = errors.New("database schema is dirty")
+)
+
+const (
+	// NoTransaction as the first line of a migration runs it outside a
+	// transaction, for statements such as CREATE INDEX CONCURRENTLY. A
+	// failure then leaves the version marked dirty.
+	NoTransaction = "-- migrate:no-transaction"
+
+	// lockID keys the advisory lock that keeps replicas from migrating at
+	// the same time
+	lockID = 4_715_052_019
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)(?:\.([a-z]+))?\.sql$`)
+
+// Migration is one versioned schema change. Down is empty for changes that
+// cannot be rolled back.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and whether it is applied. Migrations applied by a
+// newer release have no Up or Down.
+type Status struct {
+	Migration
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	Dirty     bool      `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (m *appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Load reads <version>_<name>.up.sql and .down.sql pairs from fsys, ordered
+// by version. A <version>_<name>.up.<dialect>.sql file replaces the portable
+// one for that dialect, such as "sqlite"; variants for other dialects are
+// skipped.
+func Load(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	variants := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		if match[4] != "" && match[4] != dialect {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, match[2])
+		}
+
+		// fs.ReadDir sorts by name, so a variant may come before or after
+		// its portable file
+		key := match[1] + "." + match[3]
+		if match[4] == "" && variants[key] {
+			continue
+		}
+		if match[4] != "" {
+			variants[key] = true
+		}
+		if match[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Embedded returns the migrations compiled into the binary for dialect, the
+// name of a gorm.Dialector.
+func Embedded(dialect string) ([]Migration, error) {
+	sub, err := fs.Sub(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return Load(sub, dialect)
+}
+
+// Create writes empty up and down files for the next version in dir and
+// returns their paths.
+func Create(dir, name string) (string, string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", fmt.Errorf("migration name is required")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read migrations: %w", err)
+	}
+	version := int64(1)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		if existing, _ := strconv.ParseInt(match[1], 10, 64); existing >= version {
+			version = existing + 1
+		}
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%06d_%s", version, name))
+	up, down := base+".up.sql", base+".down.sql"
+	if err := os.WriteFile(up, []byte("-- "+name+"\n"), 0o644); err != nil {
+		return "", "", fmt.Errorf("failed to create migration: %w", err)
+	}
+	if err := os.WriteFile(down, []byte("-- revert "+name+"\n"), 0o644); err != nil {
+		return "", "", fmt.Errorf("failed to create migration: %w", err)
+	}
+	return up, down, nil
+}
+
+// Migrator applies migrations and records them in schema_migrations.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func New(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Status lists every known and applied migration, ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.Dirty = record.Dirty
+			status.AppliedAt = &record.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		statuses = append(statuses, Status{
+			Migration: Migration{Version: record.Version, Name: record.Name},
+			Applied:   true,
+			Dirty:     record.Dirty,
+			AppliedAt: &record.AppliedAt,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Check returns ErrDirty or ErrPending unless the schema is up to date.
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.Dirty {
+			return fmt.Errorf("%w: version %d", ErrDirty, status.Version)
+		}
+		if !status.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d", ErrPending, pending)
+	}
+	return nil
+}
+
+// Up applies every pending migration in order and returns how many ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func() error {
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			if status.Dirty {
+				return fmt.Errorf("%w: version %d", ErrDirty, status.Version)
+			}
+		}
+
+		for _, status := range statuses {
+			if status.Applied {
+				continue
+			}
+			logger.Info(fmt.Sprintf("Applying migration %d_%s", status.Version, status.Name))
+			if err := m.apply(ctx, status.Migration, true); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down rolls back the last steps applied migrations and returns how many
+// were rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func() error {
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(statuses) - 1; i >= 0 && count < steps; i-- {
+			status := statuses[i]
+			if !status.Applied {
+				continue
+			}
+			if status.Dirty {
+				return fmt.Errorf("%w: version %d", ErrDirty, status.Version)
+			}
+			if status.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be rolled back", status.Version, status.Name)
+			}
+			logger.Info(fmt.Sprintf("Rolling back migration %d_%s", status.Version, status.Name))
+			if err := m.apply(ctx, status.Migration, false); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Force records version as the last applied migration without running any
+// SQL, for recovering from a dirty schema once it was fixed by hand. Every
+// known migration up to version is marked applied and clean, and every
+// later one pending. Version 0 marks all migrations pending.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	known := version == 0
+	for _, migration := range m.migrations {
+		known = known || migration.Version == version
+	}
+	if !known {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func() error {
+		if err := m.ensureTable(ctx); err != nil {
+			return err
+		}
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("version > ?", version).Delete(&appliedMigration{}).Error; err != nil {
+				return err
+			}
+			for _, migration := range m.migrations {
+				if migration.Version > version {
+					continue
+				}
+				record, ok := applied[migration.Version]
+				if ok && !record.Dirty {
+					continue
+				}
+				if !ok {
+					record = appliedMigration{Version: migration.Version, Name: migration.Name}
+				}
+				record.Dirty = false
+				record.AppliedAt = time.Now()
+				if err := tx.Save(&record).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to force migration version %d: %w", version, err)
+		}
+		return nil
+	})
+}
+
+// apply runs one direction of migration and records it in the same
+// transaction, or around it for NoTransaction migrations.
+func (m *Migrator) apply(ctx context.Context, migration Migration, up bool) error {
+	script := migration.Down
+	if up {
+		script = migration.Up
+	}
+	db := m.db.WithContext(ctx)
+
+	record := func(tx *gorm.DB, dirty bool) error {
+		if !up && !dirty {
+			return tx.Delete(&appliedMigration{}, migration.Version).Error
+		}
+		return tx.Save(&appliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Dirty:     dirty,
+			AppliedAt: time.Now(),
+		}).Error
+	}
+
+	if !strings.HasPrefix(strings.TrimSpace(script), NoTransaction) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(script).Error; err != nil {
+				return err
+			}
+			return record(tx, false)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		return nil
+	}
+
+	if err := record(db, true); err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if err := db.Exec(script).Error; err != nil {
+		return fmt.Errorf("failed to apply migration %d_%s, schema left dirty: %w", migration.Version, migration.Name, err)
+	}
+	if err := record(db, false); err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	err := m.db.WithContext(ctx).Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		dirty BOOLEAN NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]appliedMigration, error) {
+	var records []appliedMigration
+	if err := m.db.WithContext(ctx).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	applied := make(map[int64]appliedMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// withLock holds a Postgres advisory lock on its own connection while fn
+// runs, so only one replica migrates at a time.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if m.db.Dialector.Name() != "postgres" {
+		return fn()
+	}
+
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
 	if err != nil {
-		logger.Fatal("Failed to migrate database:", err)
-		return
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID); err != nil {
+			logger.Error("Failed to release migration lock: " + err.Error())
+		}
+	}()
+	return fn()
+}
+
+// Migrate checks the schema at startup. With autoApply pending migrations are
+// applied first; otherwise a pending or dirty schema is fatal.
+func Migrate(db *gorm.DB, autoApply bool) {
+	ctx := context.Background()
+	migrations, err := Embedded(db.Dialector.Name())
+	if err != nil {
+		logger.Fatal("Failed to load migrations:", err)
+	}
+	migrator := New(db, migrations)
+
+	if autoApply {
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			logger.Fatal("Failed to migrate database:", err)
+		}
+		if count > 0 {
+			logger.Info(fmt.Sprintf("Applied %d migration(s)", count))
+		}
+	}
+
+	if err := migrator.Check(ctx); err != nil {
+		logger.Fatal("Refusing to start: ", err, "; run `migrate up` or set database.auto_migrate")
 	}
 }
@@ -0,0 +1,292 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		wantErr  bool
+		versions []int64
+		up       []string
+	}{
+		{
+			name: "orders migrations by version",
+			files: fstest.MapFS{
+				"000010_add_index.up.sql":      {Data: []byte("CREATE INDEX")},
+				"000002_add_column.up.sql":     {Data: []byte("ALTER TABLE")},
+				"000002_add_column.down.sql":   {Data: []byte("ALTER TABLE")},
+				"000001_initial_schema.up.sql": {Data: []byte("CREATE TABLE")},
+			},
+			versions: []int64{1, 2, 10},
+		},
+		{
+			name: "prefers the variant for the dialect",
+			files: fstest.MapFS{
+				"000001_initial_schema.up.sql":          {Data: []byte("CREATE TABLE portable")},
+				"000001_initial_schema.up.sqlite.sql":   {Data: []byte("CREATE TABLE sqlite")},
+				"000001_initial_schema.up.postgres.sql": {Data: []byte("CREATE TABLE postgres")},
+				"000002_postgres_only.up.postgres.sql":  {Data: []byte("CREATE EXTENSION")},
+			},
+			versions: []int64{1},
+			up:       []string{"CREATE TABLE sqlite"},
+		},
+		{
+			name:    "rejects unknown files",
+			files:   fstest.MapFS{"README.md": {Data: []byte("docs")}},
+			wantErr: true,
+		},
+		{
+			name: "rejects a version used twice",
+			files: fstest.MapFS{
+				"000001_one.up.sql": {Data: []byte("SELECT 1")},
+				"000001_two.up.sql": {Data: []byte("SELECT 2")},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "rejects a migration without an up file",
+			files:   fstest.MapFS{"000001_one.down.sql": {Data: []byte("SELECT 1")}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Load(tt.files, "sqlite")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(migrations) != len(tt.versions) {
+				t.Fatalf("Load() returned %d migrations, want %d", len(migrations), len(tt.versions))
+			}
+			for i, version := range tt.versions {
+				if migrations[i].Version != version {
+					t.Errorf("migration %d has version %d, want %d", i, migrations[i].Version, version)
+				}
+			}
+			for i, up := range tt.up {
+				if migrations[i].Up != up {
+					t.Errorf("migration %d runs %q, want %q", i, migrations[i].Up, up)
+				}
+			}
+		})
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	postgres, err := Embedded("postgres")
+	if err != nil {
+		t.Fatalf("Embedded(postgres) error = %v", err)
+	}
+	sqlite, err := Embedded("sqlite")
+	if err != nil {
+		t.Fatalf("Embedded(sqlite) error = %v", err)
+	}
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("Embedded() has %d postgres and %d sqlite migrations", len(postgres), len(sqlite))
+	}
+	for i, migration := range postgres {
+		if migration.Down == "" {
+			t.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+		if sqlite[i].Version != migration.Version {
+			t.Errorf("sqlite migration %d has version %d, want %d", i, sqlite[i].Version, migration.Version)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "000003_existing.up.sql"), []byte("SELECT 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	up, down, err := Create(dir, "Add Todo Priority")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if want := filepath.Join(dir, "000004_add_todo_priority.up.sql"); up != want {
+		t.Errorf("Create() up = %s, want %s", up, want)
+	}
+	if want := filepath.Join(dir, "000004_add_todo_priority.down.sql"); down != want {
+		t.Errorf("Create() down = %s, want %s", down, want)
+	}
+
+	migrations, err := Load(os.DirFS(dir), "postgres")
+	if err != nil || len(migrations) != 2 {
+		t.Fatalf("Load() after Create() = %d migrations, %v", len(migrations), err)
+	}
+
+	if _, _, err := Create(dir, "  "); err == nil {
+		t.Error("Create() with an empty name should fail")
+	}
+}
+
+func TestMigrator_SQLite(t *testing.T) {
+	db, err := config.ConnectDatabase(config.DatabaseConfig{
+		Driver:            config.DatabaseDriverSQLite,
+		Path:              filepath.Join(t.TempDir(), "todoapp.db"),
+		MaxOpenConnection: 2,
+	})
+	if err != nil {
+		t.Fatalf("ConnectDatabase() error = %v", err)
+	}
+	embedded, err := Embedded(db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("Embedded() error = %v", err)
+	}
+	migrator := New(db, embedded)
+	ctx := context.Background()
+
+	if err := migrator.Check(ctx); !errors.Is(err, ErrPending) {
+		t.Fatalf("Check() before Up() = %v, want ErrPending", err)
+	}
+	if count, err := migrator.Up(ctx); err != nil || count != len(embedded) {
+		t.Fatalf("Up() = %d, %v, want %d", count, err, len(embedded))
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatalf("Check() after Up() = %v", err)
+	}
+	if err := db.Exec("INSERT INTO todos (title, description) VALUES ('t', 'd')").Error; err != nil {
+		t.Fatalf("schema is not usable: %v", err)
+	}
+	if count, err := migrator.Down(ctx, len(embedded)); err != nil || count != len(embedded) {
+		t.Fatalf("Down() = %d, %v, want %d", count, err, len(embedded))
+	}
+	if db.Migrator().HasTable("todos") {
+		t.Error("Down() left the todos table behind")
+	}
+}
+
+// baselineTodo is domain.Todo as of the last release that created its
+// schema with GORM AutoMigrate.
+type baselineTodo struct {
+	ID          int        `gorm:"primaryKey"`
+	Title       string     `gorm:"type:varchar(100);not null"`
+	Description string     `gorm:"type:varchar(255);not null"`
+	Category    string     `gorm:"type:varchar(50);default:'default'"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime"`
+	DoneAt      *time.Time `gorm:"type:timestamp;default:null"`
+}
+
+func (t *baselineTodo) TableName() string {
+	return "todos"
+}
+
+func TestMigrator_UpgradesAutoMigratedSchema(t *testing.T) {
+	db, err := config.ConnectDatabase(config.DatabaseConfig{
+		Driver:            config.DatabaseDriverSQLite,
+		Path:              filepath.Join(t.TempDir(), "todoapp.db"),
+		MaxOpenConnection: 2,
+	})
+	if err != nil {
+		t.Fatalf("ConnectDatabase() error = %v", err)
+	}
+	if err := db.AutoMigrate(&baselineTodo{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+	existing := &baselineTodo{Title: "existing", Description: "d"}
+	if err := db.Create(existing).Error; err != nil {
+		t.Fatalf("Create() on the baseline schema error = %v", err)
+	}
+
+	embedded, err := Embedded(db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("Embedded() error = %v", err)
+	}
+	ctx := context.Background()
+	if count, err := New(db, embedded).Up(ctx); err != nil || count != len(embedded) {
+		t.Fatalf("Up() = %d, %v, want %d", count, err, len(embedded))
+	}
+
+	todo := &domain.Todo{Title: "new", Description: "d"}
+	if err := db.Create(todo).Error; err != nil {
+		t.Fatalf("Create() after Up() error = %v", err)
+	}
+	snoozedUntil := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err := db.Model(&domain.Todo{ID: existing.ID}).Update("snoozed_until", snoozedUntil).Error; err != nil {
+		t.Fatalf("Update() after Up() error = %v", err)
+	}
+
+	var got domain.Todo
+	if err := db.First(&got, existing.ID).Error; err != nil {
+		t.Fatalf("First() error = %v", err)
+	}
+	if got.Title != "existing" || got.SnoozedUntil == nil || !got.SnoozedUntil.Equal(snoozedUntil) {
+		t.Errorf("existing todo after Up() = %+v, want it kept and snoozed until %v", got, snoozedUntil)
+	}
+}
+
+func TestMigrator_Force(t *testing.T) {
+	db, err := config.ConnectDatabase(config.DatabaseConfig{
+		Driver:            config.DatabaseDriverSQLite,
+		Path:              filepath.Join(t.TempDir(), "todoapp.db"),
+		MaxOpenConnection: 2,
+	})
+	if err != nil {
+		t.Fatalf("ConnectDatabase() error = %v", err)
+	}
+	migrator := New(db, []Migration{
+		{Version: 1, Name: "create_a", Up: "CREATE TABLE a (id INTEGER)", Down: "DROP TABLE a"},
+		{Version: 2, Name: "create_b", Up: NoTransaction + "\nCREATE TABLE b (", Down: "DROP TABLE b"},
+		{Version: 3, Name: "create_c", Up: "CREATE TABLE c (id INTEGER)", Down: "DROP TABLE c"},
+	})
+	ctx := context.Background()
+
+	if _, err := migrator.Up(ctx); err == nil {
+		t.Fatal("Up() with a broken no-transaction migration should fail")
+	}
+	if err := migrator.Check(ctx); !errors.Is(err, ErrDirty) {
+		t.Fatalf("Check() after a failed migration = %v, want ErrDirty", err)
+	}
+
+	tests := []struct {
+		name        string
+		version     int64
+		wantErr     bool
+		wantApplied []int64
+	}{
+		{name: "unknown version", version: 7, wantErr: true, wantApplied: []int64{1, 2}},
+		{name: "back to before the dirty migration", version: 1, wantApplied: []int64{1}},
+		{name: "past a migration fixed by hand", version: 2, wantApplied: []int64{1, 2}},
+		{name: "nothing applied", version: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := migrator.Force(ctx, tt.version); (err != nil) != tt.wantErr {
+				t.Fatalf("Force(%d) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			}
+			statuses, err := migrator.Status(ctx)
+			if err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+			var applied []int64
+			for _, status := range statuses {
+				if status.Applied {
+					applied = append(applied, status.Version)
+				}
+				if status.Dirty && !tt.wantErr {
+					t.Errorf("version %d is still dirty", status.Version)
+				}
+			}
+			if !slices.Equal(applied, tt.wantApplied) {
+				t.Errorf("applied versions = %v, want %v", applied, tt.wantApplied)
+			}
+		})
+	}
+}
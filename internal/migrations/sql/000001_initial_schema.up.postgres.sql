@@ -0,0 +1,12 @@
+-- The todos table as GORM AutoMigrate created it before versioned
+-- migrations. IF NOT EXISTS lets databases created that way record this
+-- version without changes; later versions bring them up to date.
+CREATE TABLE IF NOT EXISTS todos (
+    id BIGSERIAL PRIMARY KEY,
+    title VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    category VARCHAR(50) DEFAULT 'default',
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    done_at TIMESTAMP DEFAULT NULL
+);
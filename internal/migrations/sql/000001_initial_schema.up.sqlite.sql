@@ -0,0 +1,10 @@
+-- SQLite variant of the initial schema; the down migration is shared.
+CREATE TABLE IF NOT EXISTS todos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    category VARCHAR(50) DEFAULT 'default',
+    created_at DATETIME,
+    updated_at DATETIME,
+    done_at DATETIME DEFAULT NULL
+);
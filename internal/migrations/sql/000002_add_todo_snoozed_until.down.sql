@@ -0,0 +1 @@
+ALTER TABLE todos DROP COLUMN snoozed_until;
@@ -0,0 +1,2 @@
+-- Releases that ran GORM AutoMigrate may already have the column.
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS snoozed_until TIMESTAMP DEFAULT NULL;
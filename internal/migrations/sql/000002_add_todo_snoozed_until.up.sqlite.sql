@@ -0,0 +1,3 @@
+-- SQLite has no ADD COLUMN IF NOT EXISTS; SQLite databases were always
+-- created by these migrations, so the column cannot exist yet.
+ALTER TABLE todos ADD COLUMN snoozed_until DATETIME DEFAULT NULL;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS processed_messages (
+    consumer VARCHAR(100) NOT NULL,
+    message_id VARCHAR(64) NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (consumer, message_id)
+);
+CREATE INDEX IF NOT EXISTS idx_processed_messages_processed_at ON processed_messages (processed_at);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id BIGSERIAL PRIMARY KEY,
+    message_id VARCHAR(36) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    routing_key VARCHAR(255),
+    content_type VARCHAR(100),
+    payload BYTEA NOT NULL,
+    due_at TIMESTAMPTZ NOT NULL,
+    attempts BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ,
+    sent_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_messages_message_id ON scheduled_messages (message_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due_at ON scheduled_messages (due_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_sent_at ON scheduled_messages (sent_at);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id VARCHAR(36) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    routing_key VARCHAR(255),
+    content_type VARCHAR(100),
+    payload BLOB NOT NULL,
+    due_at DATETIME NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at DATETIME NOT NULL,
+    created_at DATETIME,
+    sent_at DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_messages_message_id ON scheduled_messages (message_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due_at ON scheduled_messages (due_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_sent_at ON scheduled_messages (sent_at);
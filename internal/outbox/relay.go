@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/store"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+	defaultMaxBackoff   = 5 * time.Minute
+	defaultMaxAttempts  = 20
+	defaultMaxPass      = 30 * time.Second
+)
+
+var OutboxEventsParked = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "outbox_events_parked_total",
+		Help: "Total number of outbox events the relay gave up on after max attempts",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(OutboxEventsParked)
+}
+
+// Relay publishes outbox events in insertion order. The pending batch is
+// locked for the duration of a pass, so concurrent relays take turns rather
+// than reorder events. A failed event is retried after a backoff without
+// holding up the ones behind it, and parked once it has failed maxAttempts
+// times.
+//
+// The pass transaction holds its row locks while publishing, so a pass stops
+// starting new publishes after maxPass. With each publish bounded by the
+// publisher's confirm timeout, locks are held for at most maxPass plus one
+// publish timeout.
+type Relay struct {
+	store        store.Store
+	publisher    domain.EventPublisher
+	pollInterval time.Duration
+	batchSize    int
+	maxBackoff   time.Duration
+	maxAttempts  int
+	maxPass      time.Duration
+}
+
+func NewRelay(store store.Store, publisher domain.EventPublisher, pollInterval time.Duration, batchSize int, maxBackoff time.Duration, maxAttempts int, maxPass time.Duration) *Relay {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	if maxPass <= 0 {
+		maxPass = defaultMaxPass
+	}
+	return &Relay{
+		store:        store,
+		publisher:    publisher,
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+		maxBackoff:   maxBackoff,
+		maxAttempts:  maxAttempts,
+		maxPass:      maxPass,
+	}
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	logger.Info("Outbox relay started")
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+
+		// Keep going while full batches are being sent.
+		for {
+			sent, err := r.RelayOnce(ctx)
+			if err != nil {
+				logger.Error("Outbox relay failed: " + err.Error())
+				break
+			}
+			if sent < r.batchSize {
+				break
+			}
+		}
+	}
+}
+
+// RelayOnce publishes one batch and returns how many events were sent.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	sent := 0
+	err := r.store.WithTx(ctx, func(tx store.Store) error {
+		events, err := tx.OutboxRepository().FetchPending(ctx, r.batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to fetch outbox events: %w", err)
+		}
+
+		start := time.Now()
+		for _, event := range events {
+			if time.Since(start) >= r.maxPass {
+				logger.Warn(fmt.Sprintf("Outbox relay pass exceeded %s, leaving the rest of the batch for the next pass", r.maxPass))
+				return nil
+			}
+
+			err := r.publisher.Publish(ctx, domain.Message{
+				RoutingKey:  event.RoutingKey,
+				ContentType: event.ContentType,
+				MessageID:   event.EventID,
+				Type:        event.EventType,
+				Body:        event.Payload,
+			})
+			if err != nil {
+				if err := r.markFailed(ctx, tx, event, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := tx.OutboxRepository().MarkSent(ctx, event.ID); err != nil {
+				return fmt.Errorf("failed to mark outbox event %s as sent: %w", event.EventID, err)
+			}
+			sent++
+		}
+		return nil
+	})
+	return sent, err
+}
+
+// markFailed schedules a retry of event, or parks it once it has used up
+// its attempts.
+func (r *Relay) markFailed(ctx context.Context, tx store.Store, event *domain.OutboxEvent, publishErr error) error {
+	attempt := event.Attempts + 1
+	if attempt >= r.maxAttempts {
+		logger.Error(fmt.Sprintf("Giving up on outbox event %s after %d attempts: %v", event.EventID, attempt, publishErr))
+		if err := tx.OutboxRepository().MarkParked(ctx, event.ID, publishErr.Error()); err != nil {
+			return fmt.Errorf("failed to park outbox event %s: %w", event.EventID, err)
+		}
+		OutboxEventsParked.Inc()
+		return nil
+	}
+
+	next := time.Now().Add(r.backoff(attempt))
+	logger.Warn(fmt.Sprintf("Failed to publish outbox event %s (attempt %d), retrying at %s: %v",
+		event.EventID, attempt, next.Format(time.RFC3339), publishErr))
+	if err := tx.OutboxRepository().MarkFailed(ctx, event.ID, publishErr.Error(), next); err != nil {
+		return fmt.Errorf("failed to mark outbox event %s as failed: %w", event.EventID, err)
+	}
+	return nil
+}
+
+func (r *Relay) backoff(attempt int) time.Duration {
+	return backoff(r.pollInterval, r.maxBackoff, attempt)
+}
+
+// backoff doubles base for every attempt after the first, up to max.
+func backoff(base, max time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
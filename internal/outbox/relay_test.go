@@ -0,0 +1,215 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/store"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeOutbox keeps outbox events in memory
+type fakeOutbox struct {
+	events []*domain.OutboxEvent
+}
+
+func (f *fakeOutbox) Add(ctx context.Context, event *domain.OutboxEvent) (*domain.OutboxEvent, error) {
+	event.ID = int64(len(f.events) + 1)
+	f.events = append(f.events, event)
+	return event, nil
+}
+
+func (f *fakeOutbox) FetchPending(ctx context.Context, limit int) ([]*domain.OutboxEvent, error) {
+	var pending []*domain.OutboxEvent
+	now := time.Now()
+	for _, event := range f.events {
+		if event.SentAt == nil && event.FailedAt == nil && !event.AvailableAt.After(now) && len(pending) < limit {
+			pending = append(pending, event)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeOutbox) MarkSent(ctx context.Context, id int64) error {
+	now := time.Now()
+	f.events[id-1].SentAt = &now
+	return nil
+}
+
+func (f *fakeOutbox) MarkFailed(ctx context.Context, id int64, lastError string, availableAt time.Time) error {
+	event := f.events[id-1]
+	event.Attempts++
+	event.LastError = lastError
+	event.AvailableAt = availableAt
+	return nil
+}
+
+func (f *fakeOutbox) MarkParked(ctx context.Context, id int64, lastError string) error {
+	now := time.Now()
+	event := f.events[id-1]
+	event.Attempts++
+	event.LastError = lastError
+	event.FailedAt = &now
+	return nil
+}
+
+// fakeStore only provides the outbox and scheduled message repositories
+type fakeStore struct {
+	outbox    *fakeOutbox
+	scheduled *fakeScheduled
+}
+
+func (f *fakeStore) TodoRepository() domain.TodoRepository {
+	return nil
+}
+
+func (f *fakeStore) OutboxRepository() domain.OutboxRepository {
+	return f.outbox
+}
+
+func (f *fakeStore) ProcessedMessageRepository() domain.ProcessedMessageRepository {
+	return nil
+}
+
+func (f *fakeStore) ScheduledMessageRepository() domain.ScheduledMessageRepository {
+	return f.scheduled
+}
+
+func (f *fakeStore) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	return fn(f)
+}
+
+// fakePublisher fails for message IDs in failFor and takes latency per
+// publish
+type fakePublisher struct {
+	published []string
+	delays    []time.Duration
+	failFor   map[string]bool
+	latency   time.Duration
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, msg domain.Message) error {
+	time.Sleep(f.latency)
+	if f.failFor[msg.MessageID] {
+		return errors.New("broker unavailable")
+	}
+	f.published = append(f.published, msg.MessageID)
+	f.delays = append(f.delays, msg.Delay)
+	return nil
+}
+
+func newFakeOutbox(ids ...string) *fakeOutbox {
+	outbox := &fakeOutbox{}
+	for _, id := range ids {
+		_, _ = outbox.Add(context.Background(), &domain.OutboxEvent{EventID: id, AvailableAt: time.Now().Add(-time.Second)})
+	}
+	return outbox
+}
+
+func TestRelay_PublishesInOrder(t *testing.T) {
+	outbox := newFakeOutbox("a", "b", "c")
+	publisher := &fakePublisher{}
+	relay := NewRelay(&fakeStore{outbox: outbox}, publisher, time.Second, 2, time.Minute, 3, time.Minute)
+
+	sent, err := relay.RelayOnce(context.Background())
+	if err != nil || sent != 2 {
+		t.Fatalf("RelayOnce() = %d, %v, want 2, nil", sent, err)
+	}
+	sent, err = relay.RelayOnce(context.Background())
+	if err != nil || sent != 1 {
+		t.Fatalf("RelayOnce() = %d, %v, want 1, nil", sent, err)
+	}
+
+	want := []string{"a", "b", "c"}
+	for i, id := range want {
+		if i >= len(publisher.published) || publisher.published[i] != id {
+			t.Fatalf("published = %v, want %v", publisher.published, want)
+		}
+	}
+}
+
+func TestRelay_FailureDoesNotBlockLaterEvents(t *testing.T) {
+	outbox := newFakeOutbox("a", "b", "c")
+	publisher := &fakePublisher{failFor: map[string]bool{"b": true}}
+	relay := NewRelay(&fakeStore{outbox: outbox}, publisher, time.Second, 10, time.Minute, 3, time.Minute)
+
+	sent, err := relay.RelayOnce(context.Background())
+	if err != nil || sent != 2 {
+		t.Fatalf("RelayOnce() = %d, %v, want 2, nil", sent, err)
+	}
+
+	failed := outbox.events[1]
+	if failed.Attempts != 1 || failed.LastError == "" || !failed.AvailableAt.After(time.Now()) || failed.FailedAt != nil {
+		t.Errorf("failed event = %+v, want one attempt with a future retry", failed)
+	}
+	if outbox.events[2].SentAt == nil {
+		t.Error("event after a failed one must still be published")
+	}
+
+	// Still backing off: nothing is published.
+	publisher.failFor = nil
+	if sent, _ := relay.RelayOnce(context.Background()); sent != 0 {
+		t.Errorf("RelayOnce() during backoff sent %d events, want 0", sent)
+	}
+
+	failed.AvailableAt = time.Now().Add(-time.Second)
+	if sent, _ := relay.RelayOnce(context.Background()); sent != 1 {
+		t.Errorf("RelayOnce() after backoff sent %d events, want 1", sent)
+	}
+}
+
+func TestRelay_ParksAfterMaxAttempts(t *testing.T) {
+	outbox := newFakeOutbox("a", "b")
+	publisher := &fakePublisher{failFor: map[string]bool{"a": true}}
+	relay := NewRelay(&fakeStore{outbox: outbox}, publisher, time.Second, 10, time.Minute, 3, time.Minute)
+	parked := testutil.ToFloat64(OutboxEventsParked)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		outbox.events[0].AvailableAt = time.Now().Add(-time.Second)
+		if _, err := relay.RelayOnce(context.Background()); err != nil {
+			t.Fatalf("RelayOnce() error = %v", err)
+		}
+	}
+
+	event := outbox.events[0]
+	if event.Attempts != 3 || event.FailedAt == nil {
+		t.Fatalf("event after 3 failures = %+v, want it parked", event)
+	}
+	if got := testutil.ToFloat64(OutboxEventsParked) - parked; got != 1 {
+		t.Errorf("outbox_events_parked_total grew by %v, want 1", got)
+	}
+
+	event.AvailableAt = time.Now().Add(-time.Second)
+	publisher.failFor = nil
+	if sent, _ := relay.RelayOnce(context.Background()); sent != 0 {
+		t.Errorf("RelayOnce() published %d events, want the parked event skipped", sent)
+	}
+}
+
+func TestRelay_StopsPassAfterMaxPass(t *testing.T) {
+	outbox := newFakeOutbox("a", "b", "c")
+	publisher := &fakePublisher{latency: 20 * time.Millisecond}
+	relay := NewRelay(&fakeStore{outbox: outbox}, publisher, time.Second, 10, time.Minute, 3, 30*time.Millisecond)
+
+	sent, err := relay.RelayOnce(context.Background())
+	if err != nil || sent != 2 {
+		t.Fatalf("RelayOnce() = %d, %v, want 2, nil", sent, err)
+	}
+	if sent, _ := relay.RelayOnce(context.Background()); sent != 1 {
+		t.Errorf("next RelayOnce() sent %d events, want the remaining 1", sent)
+	}
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	relay := NewRelay(&fakeStore{}, &fakePublisher{}, time.Second, 10, 5*time.Second, 3, time.Minute)
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := relay.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/store"
+)
+
+const defaultSchedulerPollInterval = 5 * time.Second
+
+// Scheduler publishes scheduled messages. Messages due within the horizon
+// are handed to the broker as delayed messages, which keeps broker delays
+// short while Postgres holds everything further out. With a zero horizon
+// messages are published once due, for brokers without delay support.
+type Scheduler struct {
+	store        store.Store
+	publisher    domain.EventPublisher
+	pollInterval time.Duration
+	batchSize    int
+	horizon      time.Duration
+	maxBackoff   time.Duration
+}
+
+func NewScheduler(store store.Store, publisher domain.EventPublisher, pollInterval time.Duration, batchSize int, horizon, maxBackoff time.Duration) *Scheduler {
+	if pollInterval <= 0 {
+		pollInterval = defaultSchedulerPollInterval
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	if horizon < 0 {
+		horizon = 0
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	return &Scheduler{
+		store:        store,
+		publisher:    publisher,
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+		horizon:      horizon,
+		maxBackoff:   maxBackoff,
+	}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	logger.Info("Scheduler started")
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			sent, err := s.RunOnce(ctx)
+			if err != nil {
+				logger.Error("Scheduler failed: " + err.Error())
+				break
+			}
+			if sent < s.batchSize {
+				break
+			}
+		}
+	}
+}
+
+// RunOnce publishes one batch of messages due within the horizon and
+// returns how many were sent. A failed message is retried after a backoff
+// without holding up the others.
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	sent := 0
+	err := s.store.WithTx(ctx, func(tx store.Store) error {
+		now := time.Now()
+		msgs, err := tx.ScheduledMessageRepository().FetchDue(ctx, now.Add(s.horizon), s.batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to fetch scheduled messages: %w", err)
+		}
+
+		for _, msg := range msgs {
+			delay := msg.DueAt.Sub(now)
+			if delay < 0 {
+				delay = 0
+			}
+
+			err := s.publisher.Publish(ctx, domain.Message{
+				RoutingKey:  msg.RoutingKey,
+				ContentType: msg.ContentType,
+				MessageID:   msg.MessageID,
+				Type:        msg.EventType,
+				Body:        msg.Payload,
+				Delay:       delay,
+			})
+			if err != nil {
+				next := now.Add(backoff(s.pollInterval, s.maxBackoff, msg.Attempts+1))
+				logger.Warn(fmt.Sprintf("Failed to publish scheduled message %s (attempt %d), retrying at %s: %v",
+					msg.MessageID, msg.Attempts+1, next.Format(time.RFC3339), err))
+				if err := tx.ScheduledMessageRepository().MarkFailed(ctx, msg.ID, err.Error(), next); err != nil {
+					return fmt.Errorf("failed to mark scheduled message %s as failed: %w", msg.MessageID, err)
+				}
+				continue
+			}
+
+			if err := tx.ScheduledMessageRepository().MarkSent(ctx, msg.ID); err != nil {
+				return fmt.Errorf("failed to mark scheduled message %s as sent: %w", msg.MessageID, err)
+			}
+			sent++
+		}
+		return nil
+	})
+	return sent, err
+}
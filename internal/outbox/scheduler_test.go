@@ -0,0 +1,121 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+)
+
+// fakeScheduled keeps scheduled messages in memory
+type fakeScheduled struct {
+	msgs []*domain.ScheduledMessage
+}
+
+func (f *fakeScheduled) Add(ctx context.Context, msg *domain.ScheduledMessage) (*domain.ScheduledMessage, error) {
+	msg.ID = int64(len(f.msgs) + 1)
+	if msg.AvailableAt.IsZero() {
+		msg.AvailableAt = time.Now().Add(-time.Second)
+	}
+	f.msgs = append(f.msgs, msg)
+	return msg, nil
+}
+
+func (f *fakeScheduled) FetchDue(ctx context.Context, before time.Time, limit int) ([]*domain.ScheduledMessage, error) {
+	var due []*domain.ScheduledMessage
+	for _, msg := range f.msgs {
+		if msg.SentAt == nil && !msg.DueAt.After(before) && !msg.AvailableAt.After(time.Now()) && len(due) < limit {
+			due = append(due, msg)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeScheduled) MarkSent(ctx context.Context, id int64) error {
+	now := time.Now()
+	f.msgs[id-1].SentAt = &now
+	return nil
+}
+
+func (f *fakeScheduled) MarkFailed(ctx context.Context, id int64, lastError string, availableAt time.Time) error {
+	msg := f.msgs[id-1]
+	msg.Attempts++
+	msg.LastError = lastError
+	msg.AvailableAt = availableAt
+	return nil
+}
+
+func newFakeScheduled(dueIn map[string]time.Duration, ids ...string) *fakeScheduled {
+	scheduled := &fakeScheduled{}
+	for _, id := range ids {
+		_, _ = scheduled.Add(context.Background(), &domain.ScheduledMessage{MessageID: id, DueAt: time.Now().Add(dueIn[id])})
+	}
+	return scheduled
+}
+
+func TestScheduler_RunOnce(t *testing.T) {
+	dueIn := map[string]time.Duration{
+		"overdue": -time.Minute,
+		"soon":    30 * time.Second,
+		"later":   time.Hour,
+	}
+
+	tests := []struct {
+		name          string
+		horizon       time.Duration
+		failFor       map[string]bool
+		wantPublished []string
+		wantDelayed   bool
+	}{
+		{
+			name:          "hands messages within the horizon to the broker with a delay",
+			horizon:       time.Minute,
+			wantPublished: []string{"overdue", "soon"},
+			wantDelayed:   true,
+		},
+		{
+			name:          "without a horizon only due messages are published",
+			wantPublished: []string{"overdue"},
+		},
+		{
+			name:          "a failed message does not hold up the others",
+			horizon:       time.Minute,
+			failFor:       map[string]bool{"overdue": true},
+			wantPublished: []string{"soon"},
+			wantDelayed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduled := newFakeScheduled(dueIn, "overdue", "soon", "later")
+			publisher := &fakePublisher{failFor: tt.failFor}
+			scheduler := NewScheduler(&fakeStore{scheduled: scheduled}, publisher, time.Second, 10, tt.horizon, time.Minute)
+
+			sent, err := scheduler.RunOnce(context.Background())
+			if err != nil || sent != len(tt.wantPublished) {
+				t.Fatalf("RunOnce() = %d, %v, want %d, nil", sent, err, len(tt.wantPublished))
+			}
+			for i, id := range tt.wantPublished {
+				if publisher.published[i] != id {
+					t.Fatalf("published = %v, want %v", publisher.published, tt.wantPublished)
+				}
+			}
+
+			if publisher.delays[0] != 0 && tt.failFor == nil {
+				t.Errorf("overdue message published with delay %s, want none", publisher.delays[0])
+			}
+			last := publisher.delays[len(publisher.delays)-1]
+			if tt.wantDelayed && (last <= 0 || last > 30*time.Second) {
+				t.Errorf("message due soon published with delay %s", last)
+			}
+
+			for _, msg := range scheduled.msgs {
+				if tt.failFor[msg.MessageID] && (msg.Attempts != 1 || !msg.AvailableAt.After(time.Now())) {
+					t.Errorf("failed message = %+v, want one attempt with a future retry", msg)
+				}
+			}
+		})
+	}
+}
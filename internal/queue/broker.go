@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+)
+
+// Broker is a queue driver. Both drivers use the topology described by
+// Queue(): bound work queues, per-attempt delay queues and a dead-letter
+// queue.
+type Broker interface {
+	Queue() *config.Queue
+	// Publisher publishes events to the main exchange
+	Publisher() domain.EventPublisher
+	// Consume runs one consumer session on queueName until ctx is cancelled
+	// or the session is lost.
+	Consume(ctx context.Context, queueName string, pool *Pool, consumerTag string) error
+	DeadLetters() DeadLetterQueue
+	// Depth reports how many messages are ready on queueName
+	Depth(ctx context.Context, queueName string) (int, error)
+	Ping(ctx context.Context) error
+	WaitConnected(ctx context.Context) error
+	Close() error
+}
+
+// DeadLetterQueue inspects and drains dead-lettered messages.
+type DeadLetterQueue interface {
+	List(ctx context.Context, limit int) ([]DeadLetter, error)
+	Replay(ctx context.Context, selector DLQSelector) (int, error)
+	Purge(ctx context.Context, selector DLQSelector) (int, error)
+}
+
+// NewBroker connects the driver selected by queue.driver.
+func NewBroker(queueConfig config.QueueConfig, rabbitConfig config.RabbitMQConfig) (Broker, error) {
+	switch queueConfig.Driver {
+	case config.QueueDriverMemory:
+		return NewMemoryBroker(config.NewQueue(rabbitConfig)), nil
+	case config.QueueDriverRabbitMQ, "":
+		queue, err := config.SetupRabbitMQConnection(rabbitConfig)
+		if err != nil {
+			return nil, err
+		}
+		return NewRabbitBroker(queue), nil
+	default:
+		return nil, fmt.Errorf("unknown queue driver %q", queueConfig.Driver)
+	}
+}
+
+type RabbitBroker struct {
+	queue     *config.Queue
+	publisher *RabbitPublisher
+}
+
+func NewRabbitBroker(queue *config.Queue) *RabbitBroker {
+	return &RabbitBroker{queue: queue, publisher: NewRabbitPublisher(queue)}
+}
+
+func (b *RabbitBroker) Queue() *config.Queue {
+	return b.queue
+}
+
+func (b *RabbitBroker) Publisher() domain.EventPublisher {
+	return b.publisher
+}
+
+func (b *RabbitBroker) Consume(ctx context.Context, queueName string, pool *Pool, consumerTag string) error {
+	return consume(ctx, b.queue, queueName, pool, consumerTag)
+}
+
+func (b *RabbitBroker) DeadLetters() DeadLetterQueue {
+	return NewDLQ(b.queue)
+}
+
+func (b *RabbitBroker) Depth(ctx context.Context, queueName string) (int, error) {
+	ch, err := b.queue.Channel()
+	if err != nil {
+		return 0, err
+	}
+	// A failed passive declare closes the channel, so it gets its own.
+	defer closeChannel(ch)
+
+	q, err := ch.QueueDeclarePassive(queueName, true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect queue %s: %w", queueName, err)
+	}
+	return q.Messages, nil
+}
+
+func (b *RabbitBroker) Ping(ctx context.Context) error {
+	return b.queue.Ping(ctx)
+}
+
+func (b *RabbitBroker) WaitConnected(ctx context.Context) error {
+	return b.queue.WaitConnected(ctx)
+}
+
+func (b *RabbitBroker) Close() error {
+	b.publisher.Close()
+	return b.queue.Close()
+}
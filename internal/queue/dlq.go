@@ -0,0 +1,248 @@
+package queue
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nayeem-bd/Todo-App/domain/dto"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const defaultDLQListLimit = 100
+
+type DeadLetter struct {
+	ID         string `json:"id"`
+	Event      string `json:"event"`
+	TodoID     *int   `json:"todo_id,omitempty"`
+	Reason     string `json:"failure_reason"`
+	Attempts   int    `json:"attempts"`
+	Permanent  bool   `json:"permanent"`
+	RoutingKey string `json:"routing_key"`
+	FailedAt   string `json:"failed_at,omitempty"`
+}
+
+// DLQSelector picks dead-lettered messages by ID, or every message when All
+// is set.
+type DLQSelector struct {
+	All bool     `json:"all"`
+	IDs []string `json:"ids"`
+}
+
+func (s DLQSelector) matches(id string) bool {
+	if s.All {
+		return true
+	}
+	for _, selected := range s.IDs {
+		if selected == id {
+			return true
+		}
+	}
+	return false
+}
+
+// DLQ inspects and drains the dead-letter queue. Messages are fetched with
+// basic.get and held unacked while walking the queue, so a message is only
+// removed once it has been acked; everything else is requeued at the end.
+type DLQ struct {
+	queue *config.Queue
+}
+
+func NewDLQ(queue *config.Queue) *DLQ {
+	return &DLQ{queue: queue}
+}
+
+func (d *DLQ) List(ctx context.Context, limit int) ([]DeadLetter, error) {
+	if limit <= 0 {
+		limit = defaultDLQListLimit
+	}
+
+	var letters []DeadLetter
+	err := d.walk(ctx, limit, func(msg amqp.Delivery) (bool, error) {
+		letters = append(letters, toDeadLetter(msg))
+		return false, nil
+	})
+	return letters, err
+}
+
+// Replay republishes the selected messages to the main exchange with their
+// retry headers cleared and removes them from the dead-letter queue.
+func (d *DLQ) Replay(ctx context.Context, selector DLQSelector) (int, error) {
+	ch, err := d.queue.Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	if err := ch.Confirm(false); err != nil {
+		return 0, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	replayed := 0
+	err = d.walk(ctx, 0, func(msg amqp.Delivery) (bool, error) {
+		if !selector.matches(messageID(msg)) {
+			return false, nil
+		}
+
+		routingKey, _ := msg.Headers[config.HeaderOriginalRouting].(string)
+		if routingKey == "" {
+			routingKey = d.queue.RoutingKey
+		}
+
+		confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, d.queue.ExchangeName, routingKey, false, false, amqp.Publishing{
+			Headers:       replayHeaders(msg.Headers),
+			ContentType:   msg.ContentType,
+			DeliveryMode:  amqp.Persistent,
+			CorrelationId: msg.CorrelationId,
+			MessageId:     msg.MessageId,
+			Timestamp:     msg.Timestamp,
+			Type:          msg.Type,
+			Body:          msg.Body,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to republish message: %w", err)
+		}
+		ok, err := confirm.WaitContext(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to confirm republished message: %w", err)
+		}
+		if !ok {
+			return false, fmt.Errorf("broker rejected republished message %s", messageID(msg))
+		}
+
+		replayed++
+		return true, nil
+	})
+	return replayed, err
+}
+
+func (d *DLQ) Purge(ctx context.Context, selector DLQSelector) (int, error) {
+	if selector.All {
+		ch, err := d.queue.Channel()
+		if err != nil {
+			return 0, err
+		}
+		defer ch.Close()
+
+		purged, err := ch.QueuePurge(d.queue.DeadLetterQueue, false)
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge dead-letter queue: %w", err)
+		}
+		return purged, nil
+	}
+
+	purged := 0
+	err := d.walk(ctx, 0, func(msg amqp.Delivery) (bool, error) {
+		if !selector.matches(messageID(msg)) {
+			return false, nil
+		}
+		purged++
+		return true, nil
+	})
+	return purged, err
+}
+
+// walk visits up to limit messages (all when limit is 0). fn returns true to
+// ack, and so remove, the message.
+func (d *DLQ) walk(ctx context.Context, limit int, fn func(msg amqp.Delivery) (bool, error)) error {
+	ch, err := d.queue.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	var lastTag uint64
+	var walkErr error
+	for visited := 0; limit == 0 || visited < limit; visited++ {
+		if err := ctx.Err(); err != nil {
+			walkErr = err
+			break
+		}
+
+		msg, ok, err := ch.Get(d.queue.DeadLetterQueue, false)
+		if err != nil {
+			walkErr = fmt.Errorf("failed to read dead-letter queue: %w", err)
+			break
+		}
+		if !ok {
+			break
+		}
+		lastTag = msg.DeliveryTag
+
+		remove, err := fn(msg)
+		if err != nil {
+			walkErr = err
+			break
+		}
+		if remove {
+			if err := msg.Ack(false); err != nil {
+				walkErr = fmt.Errorf("failed to ack message: %w", err)
+				break
+			}
+		}
+	}
+
+	if lastTag > 0 {
+		if err := ch.Nack(lastTag, true, true); err != nil && walkErr == nil {
+			walkErr = fmt.Errorf("failed to requeue messages: %w", err)
+		}
+	}
+	return walkErr
+}
+
+func toDeadLetter(msg amqp.Delivery) DeadLetter {
+	letter := DeadLetter{
+		ID:         messageID(msg),
+		Attempts:   Attempts(msg),
+		RoutingKey: msg.RoutingKey,
+	}
+	letter.Reason, _ = msg.Headers[config.HeaderFailureReason].(string)
+	letter.Permanent, _ = msg.Headers[config.HeaderPermanent].(bool)
+	letter.FailedAt, _ = msg.Headers[config.HeaderFailedAt].(string)
+	if key, ok := msg.Headers[config.HeaderOriginalRouting].(string); ok {
+		letter.RoutingKey = key
+	}
+
+	if event, err := dto.DecodeEvent(msg.Body); err == nil {
+		letter.Event = event.Type
+		var data struct {
+			TodoID *int `json:"todo_id"`
+		}
+		if json.Unmarshal(event.Data, &data) == nil {
+			letter.TodoID = data.TodoID
+		}
+	}
+	return letter
+}
+
+// messageID falls back to a digest for publishers that do not set a message
+// ID, so the same message keeps the same ID between calls. The digest covers
+// the failure headers as well as the body, so copies of one message that
+// failed separately get their own IDs. Copies that failed in the same second
+// after the same number of attempts still share an ID and are replayed or
+// purged together.
+func messageID(msg amqp.Delivery) string {
+	if msg.MessageId != "" {
+		return msg.MessageId
+	}
+	hash := sha256.New()
+	hash.Write(msg.Body)
+	for _, header := range []string{config.HeaderFailedAt, config.HeaderAttempts, config.HeaderFailureReason, config.HeaderOriginalRouting} {
+		fmt.Fprintf(hash, "\x00%v", msg.Headers[header])
+	}
+	fmt.Fprintf(hash, "\x00%d", msg.Timestamp.Unix())
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
+func replayHeaders(headers amqp.Table) amqp.Table {
+	replayed := copyHeaders(headers)
+	delete(replayed, config.HeaderAttempts)
+	delete(replayed, config.HeaderFailureReason)
+	delete(replayed, config.HeaderPermanent)
+	delete(replayed, config.HeaderFailedAt)
+	delete(replayed, config.HeaderOriginalRouting)
+	return replayed
+}
@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/nayeem-bd/Todo-App/domain/dto"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestToDeadLetter(t *testing.T) {
+	msg := amqp.Delivery{
+		Body:       []byte(`{"event":"todo_completed","todo_id":7}`),
+		RoutingKey: "todo_queue.retry.4",
+		Headers: amqp.Table{
+			config.HeaderAttempts:        int32(5),
+			config.HeaderFailureReason:   "database error",
+			config.HeaderOriginalRouting: "#.notification",
+		},
+	}
+
+	letter := toDeadLetter(msg)
+	if letter.Event != dto.EventTodoCompleted || letter.TodoID == nil || *letter.TodoID != 7 {
+		t.Errorf("toDeadLetter() event = %q, todo_id = %v", letter.Event, letter.TodoID)
+	}
+	if letter.Attempts != 5 || letter.Reason != "database error" {
+		t.Errorf("toDeadLetter() attempts = %d, reason = %q", letter.Attempts, letter.Reason)
+	}
+	if letter.RoutingKey != "#.notification" {
+		t.Errorf("toDeadLetter() routing key = %q, want the original one", letter.RoutingKey)
+	}
+	if letter.ID == "" || letter.ID != messageID(msg) {
+		t.Errorf("toDeadLetter() ID = %q should be stable", letter.ID)
+	}
+
+	headers := replayHeaders(msg.Headers)
+	if len(headers) != 0 {
+		t.Errorf("replayHeaders() = %v, want retry headers removed", headers)
+	}
+}
+
+func TestMessageID(t *testing.T) {
+	failed := func(failedAt string, attempts int32) amqp.Delivery {
+		return amqp.Delivery{
+			Body: []byte(`{"event":"todo_completed","todo_id":7}`),
+			Headers: amqp.Table{
+				config.HeaderFailedAt: failedAt,
+				config.HeaderAttempts: attempts,
+			},
+		}
+	}
+	first := failed("2026-10-19T10:00:00Z", 5)
+
+	tests := []struct {
+		name     string
+		msg      amqp.Delivery
+		wantSame bool
+	}{
+		{name: "the same delivery", msg: failed("2026-10-19T10:00:00Z", 5), wantSame: true},
+		{name: "the same body failing at another time", msg: failed("2026-10-19T10:05:00Z", 5)},
+		{name: "the same body after other attempts", msg: failed("2026-10-19T10:00:00Z", 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := messageID(tt.msg) == messageID(first); same != tt.wantSame {
+				t.Errorf("messageID() same = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+
+	withID := first
+	withID.MessageId = "evt-1"
+	if got := messageID(withID); got != "evt-1" {
+		t.Errorf("messageID() = %q, want the message ID", got)
+	}
+}
@@ -0,0 +1,426 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/events"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/utils"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// MemoryBroker is an in-process broker for local development and tests. It
+// mirrors the RabbitMQ topology: the exchange routes to the bound queues by
+// topic pattern, delay queues hand expired messages back to their work queue
+// and the dead-letter exchange feeds the dead-letter queue. Deliveries are
+// acked and nacked like AMQP ones. Messages do not survive a restart.
+type MemoryBroker struct {
+	queue *config.Queue
+
+	mu      sync.Mutex
+	queues  map[string]*memoryQueue
+	delays  map[string]memoryDelay
+	unacked map[uint64]unackedDelivery
+	nextTag uint64
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type memoryQueue struct {
+	ready []amqp.Delivery
+	// changed is closed and replaced whenever a message becomes ready
+	changed chan struct{}
+}
+
+type memoryDelay struct {
+	ttl    time.Duration
+	target string
+}
+
+type unackedDelivery struct {
+	queue    string
+	delivery amqp.Delivery
+}
+
+func NewMemoryBroker(queue *config.Queue) *MemoryBroker {
+	b := &MemoryBroker{
+		queue:   queue,
+		queues:  make(map[string]*memoryQueue),
+		delays:  make(map[string]memoryDelay),
+		unacked: make(map[uint64]unackedDelivery),
+		done:    make(chan struct{}),
+	}
+	for _, binding := range queue.Bindings {
+		b.queues[binding.Queue] = &memoryQueue{changed: make(chan struct{})}
+		for attempt := 1; attempt < queue.Retry.MaxAttempts; attempt++ {
+			b.delays[config.RetryQueueName(binding.Queue, attempt)] = memoryDelay{
+				ttl:    queue.Retry.Delay(attempt),
+				target: binding.Queue,
+			}
+		}
+	}
+	b.queues[queue.DeadLetterQueue] = &memoryQueue{changed: make(chan struct{})}
+	return b
+}
+
+func (b *MemoryBroker) Queue() *config.Queue {
+	return b.queue
+}
+
+func (b *MemoryBroker) Publisher() domain.EventPublisher {
+	return b
+}
+
+// Publish implements domain.EventPublisher like RabbitPublisher: messages
+// go to the main exchange and unroutable ones are rejected.
+func (b *MemoryBroker) Publish(ctx context.Context, msg domain.Message) error {
+	routingKey := msg.RoutingKey
+	if routingKey == "" {
+		routingKey = b.queue.RoutingKey
+	}
+	messageID := msg.MessageID
+	if messageID == "" {
+		messageID = utils.NewID()
+	}
+	publishing := amqp.Publishing{
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
+		Timestamp:    time.Now(),
+		Type:         msg.Type,
+		Body:         msg.Body,
+	}
+	if msg.Delay > 0 {
+		if err := b.Ping(ctx); err != nil {
+			return err
+		}
+		time.AfterFunc(msg.Delay, func() {
+			if err := b.PublishWithContext(context.Background(), b.queue.ExchangeName, routingKey, false, false, publishing); err != nil {
+				logger.Warn("Dropping delayed message ", messageID, ": ", err)
+			}
+		})
+		return nil
+	}
+	return b.PublishWithContext(ctx, b.queue.ExchangeName, routingKey, true, false, publishing)
+}
+
+// PublishWithContext implements Publisher, so the Retrier reroutes failed
+// messages through the same delay and dead-letter queues as on RabbitMQ.
+func (b *MemoryBroker) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if err := b.Ping(ctx); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.publishLocked(exchange, key, mandatory, msg)
+}
+
+func (b *MemoryBroker) publishLocked(exchange, key string, mandatory bool, msg amqp.Publishing) error {
+	var targets []string
+	switch exchange {
+	case "":
+		if delay, ok := b.delays[key]; ok {
+			b.schedule(delay, msg)
+			return nil
+		}
+		if _, ok := b.queues[key]; ok {
+			targets = append(targets, key)
+		}
+	case b.queue.ExchangeName:
+		for _, binding := range b.queue.Bindings {
+			for _, pattern := range binding.RoutingKeys {
+				if events.MatchRoutingKey(pattern, key) {
+					targets = append(targets, binding.Queue)
+					break
+				}
+			}
+		}
+	case b.queue.DeadLetterExchange:
+		targets = append(targets, b.queue.DeadLetterQueue)
+	default:
+		return fmt.Errorf("failed to publish message: no exchange %q", exchange)
+	}
+
+	if len(targets) == 0 {
+		if mandatory {
+			return fmt.Errorf("%w: no queue bound to %s", domain.ErrPublishUnroutable, key)
+		}
+		return nil
+	}
+	for _, target := range targets {
+		b.enqueueLocked(target, toDelivery(exchange, key, msg), false)
+	}
+	return nil
+}
+
+// schedule moves a message from a delay queue to its work queue once the
+// TTL has passed, as dead-lettering through the default exchange does.
+func (b *MemoryBroker) schedule(delay memoryDelay, msg amqp.Publishing) {
+	time.AfterFunc(delay.ttl, func() {
+		select {
+		case <-b.done:
+			return
+		default:
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.enqueueLocked(delay.target, toDelivery("", delay.target, msg), false)
+	})
+}
+
+func (b *MemoryBroker) enqueueLocked(name string, delivery amqp.Delivery, front bool) {
+	q := b.queues[name]
+	if front {
+		q.ready = append([]amqp.Delivery{delivery}, q.ready...)
+	} else {
+		q.ready = append(q.ready, delivery)
+	}
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// next hands out the oldest ready message, or a channel that is closed once
+// one arrives.
+func (b *MemoryBroker) next(name string) (amqp.Delivery, bool, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queues[name]
+	if len(q.ready) == 0 {
+		return amqp.Delivery{}, false, q.changed
+	}
+	delivery := q.ready[0]
+	q.ready = q.ready[1:]
+
+	b.nextTag++
+	delivery.DeliveryTag = b.nextTag
+	delivery.Acknowledger = b
+	b.unacked[delivery.DeliveryTag] = unackedDelivery{queue: name, delivery: delivery}
+	return delivery, true, nil
+}
+
+func (b *MemoryBroker) Ack(tag uint64, multiple bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, t := range b.settledTags(tag, multiple) {
+		delete(b.unacked, t)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Nack(tag uint64, multiple bool, requeue bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, t := range b.settledTags(tag, multiple) {
+		message := b.unacked[t]
+		delete(b.unacked, t)
+		if requeue {
+			message.delivery.Redelivered = true
+			b.enqueueLocked(message.queue, message.delivery, true)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Reject(tag uint64, requeue bool) error {
+	return b.Nack(tag, false, requeue)
+}
+
+func (b *MemoryBroker) settledTags(tag uint64, multiple bool) []uint64 {
+	if !multiple {
+		if _, ok := b.unacked[tag]; !ok {
+			return nil
+		}
+		return []uint64{tag}
+	}
+	var tags []uint64
+	for t := range b.unacked {
+		if t <= tag {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+func (b *MemoryBroker) Consume(ctx context.Context, queueName string, pool *Pool, consumerTag string) error {
+	if _, ok := b.queues[queueName]; !ok {
+		return fmt.Errorf("failed to register a consumer: no queue %q", queueName)
+	}
+	pool.SetFailureHandler(NewRetrier(b, b.queue, queueName, pool.Label).HandleFailure)
+	return pool.Run(ctx, &memorySource{broker: b, queueName: queueName, cancel: make(chan struct{})})
+}
+
+func (b *MemoryBroker) DeadLetters() DeadLetterQueue {
+	return &memoryDLQ{broker: b}
+}
+
+func (b *MemoryBroker) Depth(ctx context.Context, queueName string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queueName]
+	if !ok {
+		return 0, fmt.Errorf("no queue %q", queueName)
+	}
+	return len(q.ready), nil
+}
+
+func (b *MemoryBroker) Ping(ctx context.Context) error {
+	select {
+	case <-b.done:
+		return config.ErrQueueDisconnected
+	default:
+		return nil
+	}
+}
+
+func (b *MemoryBroker) WaitConnected(ctx context.Context) error {
+	return b.Ping(ctx)
+}
+
+func (b *MemoryBroker) Close() error {
+	b.closeOnce.Do(func() { close(b.done) })
+	return nil
+}
+
+type memorySource struct {
+	broker    *MemoryBroker
+	queueName string
+	cancel    chan struct{}
+	once      sync.Once
+}
+
+func (s *memorySource) Consume() (<-chan amqp.Delivery, error) {
+	deliveries := make(chan amqp.Delivery)
+	go s.deliver(deliveries)
+	return deliveries, nil
+}
+
+func (s *memorySource) Cancel() error {
+	s.once.Do(func() { close(s.cancel) })
+	return nil
+}
+
+func (s *memorySource) deliver(deliveries chan<- amqp.Delivery) {
+	defer close(deliveries)
+	for {
+		delivery, ok, changed := s.broker.next(s.queueName)
+		if !ok {
+			select {
+			case <-changed:
+				continue
+			case <-s.cancel:
+				return
+			case <-s.broker.done:
+				return
+			}
+		}
+
+		select {
+		case deliveries <- delivery:
+		case <-s.cancel:
+			_ = s.broker.Nack(delivery.DeliveryTag, false, true)
+			return
+		case <-s.broker.done:
+			return
+		}
+	}
+}
+
+func toDelivery(exchange, key string, msg amqp.Publishing) amqp.Delivery {
+	return amqp.Delivery{
+		Headers:         copyHeaders(msg.Headers),
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		UserId:          msg.UserId,
+		AppId:           msg.AppId,
+		Exchange:        exchange,
+		RoutingKey:      key,
+		Body:            msg.Body,
+	}
+}
+
+// memoryDLQ is the DeadLetterQueue of a MemoryBroker.
+type memoryDLQ struct {
+	broker *MemoryBroker
+}
+
+func (d *memoryDLQ) List(ctx context.Context, limit int) ([]DeadLetter, error) {
+	if limit <= 0 {
+		limit = defaultDLQListLimit
+	}
+
+	d.broker.mu.Lock()
+	defer d.broker.mu.Unlock()
+
+	var letters []DeadLetter
+	for _, msg := range d.broker.queues[d.broker.queue.DeadLetterQueue].ready {
+		if len(letters) == limit {
+			break
+		}
+		letters = append(letters, toDeadLetter(msg))
+	}
+	return letters, nil
+}
+
+func (d *memoryDLQ) Replay(ctx context.Context, selector DLQSelector) (int, error) {
+	b := d.broker
+	return d.remove(selector, func(msg amqp.Delivery) error {
+		routingKey, _ := msg.Headers[config.HeaderOriginalRouting].(string)
+		if routingKey == "" {
+			routingKey = b.queue.RoutingKey
+		}
+		return b.publishLocked(b.queue.ExchangeName, routingKey, false, amqp.Publishing{
+			Headers:       replayHeaders(msg.Headers),
+			ContentType:   msg.ContentType,
+			DeliveryMode:  amqp.Persistent,
+			CorrelationId: msg.CorrelationId,
+			MessageId:     msg.MessageId,
+			Timestamp:     msg.Timestamp,
+			Type:          msg.Type,
+			Body:          msg.Body,
+		})
+	})
+}
+
+func (d *memoryDLQ) Purge(ctx context.Context, selector DLQSelector) (int, error) {
+	return d.remove(selector, func(amqp.Delivery) error { return nil })
+}
+
+// remove runs fn on every selected message and drops the ones it succeeds
+// for.
+func (d *memoryDLQ) remove(selector DLQSelector, fn func(msg amqp.Delivery) error) (int, error) {
+	d.broker.mu.Lock()
+	defer d.broker.mu.Unlock()
+
+	q := d.broker.queues[d.broker.queue.DeadLetterQueue]
+	kept := q.ready[:0]
+	removed := 0
+	var err error
+	for _, msg := range q.ready {
+		if err == nil && selector.matches(messageID(msg)) {
+			if err = fn(msg); err == nil {
+				removed++
+				continue
+			}
+		}
+		kept = append(kept, msg)
+	}
+	q.ready = kept
+	return removed, err
+}
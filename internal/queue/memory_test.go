@@ -0,0 +1,228 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/events"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func memoryTestQueue() *config.Queue {
+	return &config.Queue{
+		ExchangeName:       "todo_exchange",
+		QueueName:          "todo_queue",
+		RoutingKey:         "todo.notification",
+		DeadLetterExchange: "todo_exchange.dlx",
+		DeadLetterQueue:    "todo_queue.dlq",
+		Bindings: []config.Binding{
+			{Queue: "todo_queue", RoutingKeys: []string{"#.notification"}},
+			{Queue: "audit_queue", RoutingKeys: []string{"audit.#"}},
+		},
+		Retry: config.RetryPolicy{
+			MaxAttempts:  3,
+			InitialDelay: 10 * time.Millisecond,
+			MaxDelay:     50 * time.Millisecond,
+			Multiplier:   2,
+		},
+	}
+}
+
+// runMemoryConsumer consumes queueName with handler until the test ends.
+func runMemoryConsumer(t *testing.T, broker *MemoryBroker, queueName string, handler Handler) {
+	t.Helper()
+	runMemoryPool(t, broker, queueName, NewPool(2, handler, time.Second))
+}
+
+// runMemoryPool consumes queueName with pool until the test ends.
+func runMemoryPool(t *testing.T, broker *MemoryBroker, queueName string, pool *Pool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = broker.Consume(ctx, queueName, pool, "test")
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMemoryBroker_Publish(t *testing.T) {
+	broker := NewMemoryBroker(memoryTestQueue())
+	defer broker.Close()
+
+	var todo, audit atomic.Int32
+	runMemoryConsumer(t, broker, "todo_queue", func(ctx context.Context, msg amqp.Delivery) error {
+		todo.Add(1)
+		return nil
+	})
+	runMemoryConsumer(t, broker, "audit_queue", func(ctx context.Context, msg amqp.Delivery) error {
+		audit.Add(1)
+		return nil
+	})
+
+	ctx := context.Background()
+	if err := broker.Publish(ctx, domain.Message{Body: []byte(`{}`)}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := broker.Publish(ctx, domain.Message{RoutingKey: "audit.login", Body: []byte(`{}`)}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	err := broker.Publish(ctx, domain.Message{RoutingKey: "todo.created", Body: []byte(`{}`)})
+	if !errors.Is(err, domain.ErrPublishUnroutable) {
+		t.Errorf("Publish() error = %v, want ErrPublishUnroutable", err)
+	}
+
+	waitFor(t, "both queues to receive their message", func() bool {
+		return todo.Load() == 1 && audit.Load() == 1
+	})
+}
+
+func TestMemoryBroker_RetriesThenDeadLetters(t *testing.T) {
+	broker := NewMemoryBroker(memoryTestQueue())
+	defer broker.Close()
+
+	var calls atomic.Int32
+	runMemoryConsumer(t, broker, "todo_queue", func(ctx context.Context, msg amqp.Delivery) error {
+		calls.Add(1)
+		return errors.New("database error")
+	})
+
+	if err := broker.Publish(context.Background(), domain.Message{MessageID: "msg-1", Body: []byte(`{}`)}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	dlq := broker.DeadLetters()
+	var letters []DeadLetter
+	waitFor(t, "the message to be dead-lettered", func() bool {
+		letters, _ = dlq.List(context.Background(), 0)
+		return len(letters) == 1
+	})
+	if calls.Load() != 3 {
+		t.Errorf("handler ran %d times, want 3", calls.Load())
+	}
+	if letters[0].ID != "msg-1" || letters[0].Attempts != 3 || letters[0].Reason != "database error" {
+		t.Errorf("dead letter = %+v", letters[0])
+	}
+
+	replayed, err := dlq.Replay(context.Background(), DLQSelector{IDs: []string{"msg-1"}})
+	if err != nil || replayed != 1 {
+		t.Fatalf("Replay() = %d, %v", replayed, err)
+	}
+	waitFor(t, "the replayed message to be handled again", func() bool {
+		return calls.Load() > 3
+	})
+}
+
+func TestMemoryBroker_NackRequeues(t *testing.T) {
+	broker := NewMemoryBroker(memoryTestQueue())
+	defer broker.Close()
+
+	if err := broker.Publish(context.Background(), domain.Message{Body: []byte(`{}`)}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	first, ok, _ := broker.next("todo_queue")
+	if !ok {
+		t.Fatal("next() returned no message")
+	}
+	if err := first.Nack(false, true); err != nil {
+		t.Fatalf("Nack() error = %v", err)
+	}
+
+	second, ok, _ := broker.next("todo_queue")
+	if !ok || !second.Redelivered || second.DeliveryTag == first.DeliveryTag {
+		t.Fatalf("next() after nack = %+v, %v", second, ok)
+	}
+	if err := second.Ack(false); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if _, ok, _ := broker.next("todo_queue"); ok {
+		t.Error("acked message was delivered again")
+	}
+}
+
+func TestMemoryBroker_DelayedPublish(t *testing.T) {
+	broker := NewMemoryBroker(memoryTestQueue())
+	defer broker.Close()
+
+	received := make(chan time.Time, 1)
+	runMemoryConsumer(t, broker, "todo_queue", func(ctx context.Context, msg amqp.Delivery) error {
+		received <- time.Now()
+		return nil
+	})
+
+	start := time.Now()
+	if err := broker.Publish(context.Background(), domain.Message{Body: []byte(`{}`), Delay: 50 * time.Millisecond}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	select {
+	case at := <-received:
+		if at.Sub(start) < 50*time.Millisecond {
+			t.Errorf("delayed message delivered after %s, want at least 50ms", at.Sub(start))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the delayed message")
+	}
+}
+
+func TestMemoryBroker_Metrics(t *testing.T) {
+	broker := NewMemoryBroker(memoryTestQueue())
+	defer broker.Close()
+
+	ctx := context.Background()
+	if err := broker.Publish(ctx, domain.Message{RoutingKey: "audit.metrics", Body: []byte(`{}`)}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if depth, err := broker.Depth(ctx, "audit_queue"); err != nil || depth != 1 {
+		t.Fatalf("Depth() = %d, %v, want 1", depth, err)
+	}
+
+	registry := events.NewRegistry()
+	registry.RegisterRoute("audit.#", func(ctx context.Context, msg *events.Message) error { return nil })
+	deadLettered := testutil.ToFloat64(MessagesDeadLettered.WithLabelValues("audit.#"))
+	consumed := testutil.ToFloat64(MessagesConsumed.WithLabelValues("audit.#"))
+	acked := testutil.ToFloat64(MessagesAcked.WithLabelValues("audit.#"))
+
+	pool := NewPool(2, func(ctx context.Context, msg amqp.Delivery) error {
+		return domain.Permanent(errors.New("bad message"))
+	}, time.Second)
+	pool.SetLabeler(registry.Label)
+	runMemoryPool(t, broker, "audit_queue", pool)
+
+	waitFor(t, "the message to be dead-lettered", func() bool {
+		return testutil.ToFloat64(MessagesDeadLettered.WithLabelValues("audit.#")) == deadLettered+1
+	})
+	if got := testutil.ToFloat64(MessagesConsumed.WithLabelValues("audit.#")) - consumed; got != 1 {
+		t.Errorf("consumed = %v, want 1", got)
+	}
+	waitFor(t, "the message to be acked", func() bool {
+		return testutil.ToFloat64(MessagesAcked.WithLabelValues("audit.#")) == acked+1
+	})
+	if depth, err := broker.Depth(ctx, "audit_queue"); err != nil || depth != 0 {
+		t.Errorf("Depth() = %d, %v, want 0", depth, err)
+	}
+	if _, err := broker.Depth(ctx, "missing"); err == nil {
+		t.Error("Depth() of an unknown queue should fail")
+	}
+}
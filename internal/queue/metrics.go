@@ -0,0 +1,98 @@
+package queue
+
+import (
+	"github.com/nayeem-bd/Todo-App/internal/events"
+	"github.com/prometheus/client_golang/prometheus"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	MessagesConsumed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_messages_consumed_total",
+			Help: "Total number of messages taken off a queue by event type",
+		},
+		[]string{"type"},
+	)
+
+	MessagesAcked = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_messages_acked_total",
+			Help: "Total number of messages acked by event type, including rerouted failures",
+		},
+		[]string{"type"},
+	)
+
+	MessagesNacked = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_messages_nacked_total",
+			Help: "Total number of messages nacked back onto their queue by event type",
+		},
+		[]string{"type"},
+	)
+
+	MessagesRetried = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_messages_retried_total",
+			Help: "Total number of failed messages sent to a retry queue by event type",
+		},
+		[]string{"type"},
+	)
+
+	MessagesDeadLettered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_messages_dead_lettered_total",
+			Help: "Total number of failed messages sent to the dead-letter queue by event type",
+		},
+		[]string{"type"},
+	)
+
+	MessageDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "worker_message_duration_seconds",
+			Help:    "Time from taking a message off a queue until it is settled, by event type",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"type"},
+	)
+
+	MessagesInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "worker_messages_in_flight",
+			Help: "Number of messages currently being processed",
+		},
+	)
+
+	QueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_queue_depth",
+			Help: "Number of ready messages waiting on a consumed queue",
+		},
+		[]string{"queue"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		MessagesConsumed,
+		MessagesAcked,
+		MessagesNacked,
+		MessagesRetried,
+		MessagesDeadLettered,
+		MessageDuration,
+		MessagesInFlight,
+		QueueDepth,
+	)
+}
+
+// Labeler names a delivery for the metrics above. Labels must come from a
+// fixed set, such as the event types and routes of an events.Registry.
+type Labeler func(msg amqp.Delivery) string
+
+// label falls back to events.UnknownLabel when no labeler is set.
+func label(labeler Labeler, msg amqp.Delivery) string {
+	if labeler == nil {
+		return events.UnknownLabel
+	}
+	return labeler(msg)
+}
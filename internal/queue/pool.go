@@ -0,0 +1,311 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var ErrDeliveriesClosed = errors.New("delivery channel closed unexpectedly")
+
+// DeliverySource is the consuming side of a channel. Cancel must stop new
+// deliveries and eventually close the channel returned by Consume.
+type DeliverySource interface {
+	Consume() (<-chan amqp.Delivery, error)
+	Cancel() error
+}
+
+type Handler func(ctx context.Context, msg amqp.Delivery) error
+
+// Pool runs a number of goroutines that share one delivery channel. The
+// number can be changed with Resize while the pool is running.
+type Pool struct {
+	handler         Handler
+	onFailure       FailureHandler
+	labeler         Labeler
+	shutdownTimeout time.Duration
+
+	mu       sync.Mutex
+	size     int
+	prefetch int
+	qos      func(prefetch int) error
+	resized  chan struct{}
+}
+
+func NewPool(size int, handler Handler, shutdownTimeout time.Duration) *Pool {
+	if size <= 0 {
+		size = 1
+	}
+	return &Pool{size: size, handler: handler, shutdownTimeout: shutdownTimeout, resized: make(chan struct{}, 1)}
+}
+
+// SetLabeler sets how messages are labelled in metrics; without one every
+// message is labelled events.UnknownLabel.
+func (p *Pool) SetLabeler(labeler Labeler) {
+	p.labeler = labeler
+}
+
+// Label names msg in metrics.
+func (p *Pool) Label(msg amqp.Delivery) string {
+	return label(p.labeler, msg)
+}
+
+// SetFailureHandler reroutes failed messages instead of requeueing them.
+func (p *Pool) SetFailureHandler(onFailure FailureHandler) {
+	p.onFailure = onFailure
+}
+
+// SetQoS sets how Run gives a new consumer its prefetch window. A nil qos
+// ends the session.
+func (p *Pool) SetQoS(qos func(prefetch int) error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.qos = qos
+}
+
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// SetPrefetch changes the prefetch window. A running consumer is registered
+// again to pick it up.
+func (p *Pool) SetPrefetch(prefetch int) {
+	p.mu.Lock()
+	p.prefetch = prefetch
+	p.mu.Unlock()
+	p.notify()
+}
+
+// Resize starts or stops workers until size are running. Stopped workers
+// finish the message they are processing first.
+func (p *Pool) Resize(size int) {
+	if size <= 0 {
+		size = 1
+	}
+	p.mu.Lock()
+	p.size = size
+	p.mu.Unlock()
+	p.notify()
+}
+
+func (p *Pool) notify() {
+	select {
+	case p.resized <- struct{}{}:
+	default:
+	}
+}
+
+// window returns the prefetch window for the next consumer, or 0 when there
+// is no QoS to apply. Every worker needs an unacked message to work on, so
+// the window is never smaller than the pool.
+func (p *Pool) window() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.qos == nil {
+		return 0
+	}
+	return max(p.prefetch, p.size)
+}
+
+// applyQoS sets the prefetch window for the consumer about to be registered.
+func (p *Pool) applyQoS() (int, error) {
+	p.mu.Lock()
+	qos, prefetch, size := p.qos, p.prefetch, p.size
+	p.mu.Unlock()
+	if qos == nil {
+		return 0, nil
+	}
+
+	if prefetch < size {
+		logger.Warn(fmt.Sprintf("prefetch_count %d is lower than worker_pool_count %d, using %d", prefetch, size, size))
+		prefetch = size
+	}
+	if err := qos(prefetch); err != nil {
+		return 0, fmt.Errorf("failed to set QoS: %w", err)
+	}
+	return prefetch, nil
+}
+
+// Run consumes until ctx is cancelled. On cancellation it stops the consumer,
+// lets the workers drain what was already delivered and waits for in-flight
+// messages to be acked before returning.
+//
+// The broker applies basic.qos only to consumers registered after it, so
+// when the prefetch window changes Run cancels the consumer, lets the workers
+// drain it and registers a new one with the new window.
+func (p *Pool) Run(ctx context.Context, source DeliverySource) error {
+	window, err := p.applyQoS()
+	if err != nil {
+		return err
+	}
+	msgs, err := source.Consume()
+	if err != nil {
+		return fmt.Errorf("failed to register a consumer: %w", err)
+	}
+
+	// In-flight messages must finish even after shutdown has started.
+	processCtx := context.WithoutCancel(ctx)
+
+	// Every worker reports on exited when it returns, whether it was stopped
+	// by a resize or the delivery channel was closed.
+	exited := make(chan struct{})
+	finished := make(chan struct{})
+	defer close(finished)
+
+	var stops []chan struct{}
+	running := 0
+	start := func() {
+		stop := make(chan struct{})
+		stops = append(stops, stop)
+		running++
+		go func(msgs <-chan amqp.Delivery) {
+			defer func() {
+				select {
+				case exited <- struct{}{}:
+				case <-finished:
+				}
+			}()
+			for {
+				select {
+				case <-stop:
+					return
+				case msg, ok := <-msgs:
+					if !ok {
+						return
+					}
+					p.process(processCtx, msg)
+				}
+			}
+		}(msgs)
+	}
+	resize := func() {
+		size := p.Size()
+		for len(stops) < size {
+			start()
+		}
+		for len(stops) > size {
+			close(stops[len(stops)-1])
+			stops = stops[:len(stops)-1]
+		}
+	}
+	reconsume := func() error {
+		logger.Info(fmt.Sprintf("Prefetch window changed from %d to %d, registering the consumer again", window, p.window()))
+		if err := source.Cancel(); err != nil {
+			return fmt.Errorf("failed to cancel consumer: %w", err)
+		}
+		// The workers finish what was already delivered and exit once the
+		// delivery channel is closed.
+		for ; running > 0; running-- {
+			<-exited
+		}
+		stops = nil
+
+		if window, err = p.applyQoS(); err != nil {
+			return err
+		}
+		if msgs, err = source.Consume(); err != nil {
+			return fmt.Errorf("failed to register a consumer: %w", err)
+		}
+		resize()
+		return nil
+	}
+	resize()
+
+consuming:
+	for {
+		select {
+		case <-exited:
+			if running--; running == 0 {
+				return ErrDeliveriesClosed
+			}
+		case <-p.resized:
+			if next := p.window(); next != 0 && next != window {
+				if err := reconsume(); err != nil {
+					return err
+				}
+				continue
+			}
+			resize()
+		case <-ctx.Done():
+			break consuming
+		}
+	}
+
+	logger.Info("Stopping consumer, waiting for in-flight messages...")
+	if err := source.Cancel(); err != nil {
+		logger.Error("Failed to cancel consumer: " + err.Error())
+	}
+
+	var timeout <-chan time.Time
+	if p.shutdownTimeout > 0 {
+		timeout = time.After(p.shutdownTimeout)
+	}
+	for running > 0 {
+		select {
+		case <-exited:
+			running--
+		case <-timeout:
+			return fmt.Errorf("timed out after %s waiting for in-flight messages", p.shutdownTimeout)
+		}
+	}
+	return nil
+}
+
+func (p *Pool) process(ctx context.Context, msg amqp.Delivery) {
+	eventType := p.Label(msg)
+	MessagesConsumed.WithLabelValues(eventType).Inc()
+	MessagesInFlight.Inc()
+	start := time.Now()
+	defer func() {
+		MessagesInFlight.Dec()
+		MessageDuration.WithLabelValues(eventType).Observe(time.Since(start).Seconds())
+	}()
+
+	if err := p.handler(ctx, msg); err != nil {
+		logger.Error("Failed to process message: " + err.Error())
+		if p.onFailure != nil {
+			rerouteErr := p.onFailure(ctx, msg, err)
+			if rerouteErr == nil {
+				ack(msg, eventType)
+				return
+			}
+			logger.Error("Failed to reroute message, requeueing: " + rerouteErr.Error())
+		}
+		if err := msg.Nack(false, true); err != nil {
+			logger.Error("Failed to nack message: " + err.Error())
+			return
+		}
+		MessagesNacked.WithLabelValues(eventType).Inc()
+		return
+	}
+	ack(msg, eventType)
+}
+
+func ack(msg amqp.Delivery, eventType string) {
+	if err := msg.Ack(false); err != nil {
+		logger.Error("Failed to ack message: " + err.Error())
+		return
+	}
+	MessagesAcked.WithLabelValues(eventType).Inc()
+}
+
+type amqpSource struct {
+	ch          *amqp.Channel
+	queueName   string
+	consumerTag string
+}
+
+func (s *amqpSource) Consume() (<-chan amqp.Delivery, error) {
+	return s.ch.Consume(s.queueName, s.consumerTag, false, false, false, false, nil)
+}
+
+func (s *amqpSource) Cancel() error {
+	return s.ch.Cancel(s.consumerTag, false)
+}
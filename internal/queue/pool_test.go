@@ -0,0 +1,423 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeAcknowledger records acks and nacks by delivery tag
+type fakeAcknowledger struct {
+	mu     sync.Mutex
+	acked  []uint64
+	nacked []uint64
+}
+
+func (f *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.acked = append(f.acked, tag)
+	return nil
+}
+
+func (f *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nacked = append(f.nacked, tag)
+	return nil
+}
+
+func (f *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return f.Nack(tag, false, requeue)
+}
+
+func (f *fakeAcknowledger) counts() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.acked), len(f.nacked)
+}
+
+// fakeSource is a DeliverySource backed by a buffered channel
+type fakeSource struct {
+	msgs      chan amqp.Delivery
+	cancelled atomic.Bool
+	once      sync.Once
+}
+
+func newFakeSource(ack amqp.Acknowledger, n int) *fakeSource {
+	s := &fakeSource{msgs: make(chan amqp.Delivery, n)}
+	for i := 1; i <= n; i++ {
+		s.msgs <- amqp.Delivery{Acknowledger: ack, DeliveryTag: uint64(i)}
+	}
+	return s
+}
+
+func (s *fakeSource) Consume() (<-chan amqp.Delivery, error) {
+	return s.msgs, nil
+}
+
+func (s *fakeSource) Cancel() error {
+	s.cancelled.Store(true)
+	s.once.Do(func() { close(s.msgs) })
+	return nil
+}
+
+func TestPool_BoundedConcurrency(t *testing.T) {
+	const size = 3
+	ack := &fakeAcknowledger{}
+	source := newFakeSource(ack, 10)
+
+	var current, peak atomic.Int32
+	release := make(chan struct{})
+	handler := func(ctx context.Context, msg amqp.Delivery) error {
+		n := current.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		current.Add(-1)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- NewPool(size, handler, time.Second).Run(ctx, source) }()
+
+	deadline := time.Now().Add(time.Second)
+	for current.Load() < size && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+	cancel()
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("Pool.Run() error = %v", err)
+	}
+	if got := peak.Load(); got != size {
+		t.Errorf("peak concurrency = %d, want %d", got, size)
+	}
+	if acked, nacked := ack.counts(); acked != 10 || nacked != 0 {
+		t.Errorf("acked = %d, nacked = %d, want 10 and 0", acked, nacked)
+	}
+}
+
+func TestPool_ShutdownWaitsForInFlight(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	source := newFakeSource(ack, 2)
+
+	started := make(chan struct{}, 2)
+	handler := func(ctx context.Context, msg amqp.Delivery) error {
+		started <- struct{}{}
+		time.Sleep(50 * time.Millisecond)
+		// The handler context must survive the shutdown signal.
+		return ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- NewPool(2, handler, time.Second).Run(ctx, source) }()
+
+	<-started
+	<-started
+	cancel()
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("Pool.Run() error = %v", err)
+	}
+	if !source.cancelled.Load() {
+		t.Error("Pool.Run() should cancel the consumer on shutdown")
+	}
+	if acked, nacked := ack.counts(); acked != 2 || nacked != 0 {
+		t.Errorf("acked = %d, nacked = %d, want 2 and 0", acked, nacked)
+	}
+}
+
+func TestPool_NacksFailedMessages(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	source := newFakeSource(ack, 4)
+
+	handler := func(ctx context.Context, msg amqp.Delivery) error {
+		if msg.DeliveryTag%2 == 0 {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	// Closing the source without cancelling ctx simulates a lost consumer.
+	close(source.msgs)
+	err := NewPool(2, handler, time.Second).Run(context.Background(), source)
+	if !errors.Is(err, ErrDeliveriesClosed) {
+		t.Fatalf("Pool.Run() error = %v, want %v", err, ErrDeliveriesClosed)
+	}
+	if acked, nacked := ack.counts(); acked != 2 || nacked != 2 {
+		t.Errorf("acked = %d, nacked = %d, want 2 and 2", acked, nacked)
+	}
+}
+
+func TestPool_RequeuesUnconfirmedReroutes(t *testing.T) {
+	tests := []struct {
+		name       string
+		rerouteErr error
+		wantAcked  int
+		wantNacked int
+	}{
+		{name: "confirmed reroute acks the original", wantAcked: 1},
+		{name: "nacked reroute requeues the original", rerouteErr: domain.ErrPublishNacked, wantNacked: 1},
+		{name: "unconfirmed reroute requeues the original", rerouteErr: domain.ErrPublishTimeout, wantNacked: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack := &fakeAcknowledger{}
+			source := newFakeSource(ack, 1)
+			close(source.msgs)
+
+			handler := func(ctx context.Context, msg amqp.Delivery) error { return errors.New("boom") }
+			pool := NewPool(1, handler, time.Second)
+			pool.SetFailureHandler(func(ctx context.Context, msg amqp.Delivery, cause error) error { return tt.rerouteErr })
+
+			if err := pool.Run(context.Background(), source); !errors.Is(err, ErrDeliveriesClosed) {
+				t.Fatalf("Pool.Run() error = %v, want %v", err, ErrDeliveriesClosed)
+			}
+			if acked, nacked := ack.counts(); acked != tt.wantAcked || nacked != tt.wantNacked {
+				t.Errorf("acked = %d, nacked = %d, want %d and %d", acked, nacked, tt.wantAcked, tt.wantNacked)
+			}
+		})
+	}
+}
+
+func TestPool_ShutdownTimeout(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	source := newFakeSource(ack, 1)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := func(ctx context.Context, msg amqp.Delivery) error {
+		close(started)
+		<-release
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- NewPool(1, handler, 20*time.Millisecond).Run(ctx, source) }()
+
+	<-started
+	cancel()
+
+	if err := <-errCh; err == nil {
+		t.Fatal("Pool.Run() should time out while a message is still in flight")
+	}
+}
+
+func TestPool_Resize(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	source := newFakeSource(ack, 10)
+
+	var current atomic.Int32
+	release := make(chan struct{})
+	handler := func(ctx context.Context, msg amqp.Delivery) error {
+		current.Add(1)
+		<-release
+		current.Add(-1)
+		return nil
+	}
+	waitFor := func(want int32) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for current.Load() != want && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got := current.Load(); got != want {
+			t.Fatalf("concurrency = %d, want %d", got, want)
+		}
+	}
+
+	pool := NewPool(1, handler, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- pool.Run(ctx, source) }()
+	waitFor(1)
+
+	pool.Resize(3)
+	waitFor(3)
+
+	close(release)
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Pool.Run() error = %v", err)
+	}
+	if acked, _ := ack.counts(); acked != 10 {
+		t.Errorf("acked = %d, want 10", acked)
+	}
+}
+
+// qosSource models how RabbitMQ applies basic.qos: a consumer gets the
+// prefetch window set before it was registered and never has more unacked
+// deliveries than that, whatever Qos is called with later.
+type qosSource struct {
+	mu      sync.Mutex
+	qos     int
+	unacked int
+	tag     uint64
+	windows []int
+	stop    chan struct{}
+	acked   chan struct{}
+}
+
+func newQoSSource() *qosSource {
+	return &qosSource{acked: make(chan struct{}, 1)}
+}
+
+func (s *qosSource) Qos(prefetch int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.qos = prefetch
+	return nil
+}
+
+func (s *qosSource) Consume() (<-chan amqp.Delivery, error) {
+	s.mu.Lock()
+	window := s.qos
+	s.windows = append(s.windows, window)
+	stop := make(chan struct{})
+	s.stop = stop
+	s.mu.Unlock()
+
+	msgs := make(chan amqp.Delivery)
+	go func() {
+		defer close(msgs)
+		for {
+			s.mu.Lock()
+			full := s.unacked >= window
+			if !full {
+				s.unacked++
+				s.tag++
+			}
+			tag := s.tag
+			s.mu.Unlock()
+
+			if full {
+				select {
+				case <-stop:
+					return
+				case <-s.acked:
+				}
+				continue
+			}
+			select {
+			case msgs <- amqp.Delivery{Acknowledger: s, DeliveryTag: tag}:
+			case <-stop:
+				s.settle()
+				return
+			}
+		}
+	}()
+	return msgs, nil
+}
+
+func (s *qosSource) Cancel() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.stop)
+	return nil
+}
+
+func (s *qosSource) settle() {
+	s.mu.Lock()
+	s.unacked--
+	s.mu.Unlock()
+	select {
+	case s.acked <- struct{}{}:
+	default:
+	}
+}
+
+func (s *qosSource) Ack(tag uint64, multiple bool) error {
+	s.settle()
+	return nil
+}
+
+func (s *qosSource) Nack(tag uint64, multiple bool, requeue bool) error {
+	s.settle()
+	return nil
+}
+
+func (s *qosSource) Reject(tag uint64, requeue bool) error {
+	s.settle()
+	return nil
+}
+
+func (s *qosSource) consumerWindows() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.windows)
+}
+
+func TestPool_PrefetchChangeReconsumes(t *testing.T) {
+	source := newQoSSource()
+
+	var current, peak atomic.Int32
+	handler := func(ctx context.Context, msg amqp.Delivery) error {
+		n := current.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+		current.Add(-1)
+		return nil
+	}
+	waitForPeak := func(want int32) {
+		t.Helper()
+		peak.Store(0)
+		deadline := time.Now().Add(time.Second)
+		for peak.Load() < want && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got := peak.Load(); got != want {
+			t.Fatalf("peak concurrency = %d, want %d", got, want)
+		}
+	}
+
+	pool := NewPool(1, handler, time.Second)
+	pool.SetPrefetch(1)
+	pool.SetQoS(source.Qos)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- pool.Run(ctx, source) }()
+	waitForPeak(1)
+
+	// More workers than the old window only help once the consumer is
+	// registered again with a larger one.
+	pool.Resize(3)
+	waitForPeak(3)
+
+	pool.SetPrefetch(5)
+	deadline := time.Now().Add(time.Second)
+	for len(source.consumerWindows()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Shrinking the pool keeps the window, so the consumer stays.
+	pool.Resize(2)
+	time.Sleep(50 * time.Millisecond)
+	if got, want := source.consumerWindows(), []int{1, 3, 5}; !slices.Equal(got, want) {
+		t.Errorf("consumer prefetch windows = %v, want %v", got, want)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Pool.Run() error = %v", err)
+	}
+}
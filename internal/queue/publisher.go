@@ -0,0 +1,205 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/utils"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	defaultPublisherChannels = 4
+	defaultPublishTimeout    = 5 * time.Second
+)
+
+type publishChannel struct {
+	ch      *amqp.Channel
+	returns chan amqp.Return
+}
+
+// RabbitPublisher publishes mandatory, persistent messages in confirm mode
+// over a bounded pool of channels. Each channel is used by one publish at a
+// time, so a basic.return always belongs to the message just published.
+type RabbitPublisher struct {
+	queue   *config.Queue
+	timeout time.Duration
+	slots   chan struct{}
+	idle    chan *publishChannel
+}
+
+func NewRabbitPublisher(queue *config.Queue) *RabbitPublisher {
+	size := queue.PublisherChannels
+	if size <= 0 {
+		size = defaultPublisherChannels
+	}
+	timeout := queue.PublishTimeout
+	if timeout <= 0 {
+		timeout = defaultPublishTimeout
+	}
+	return &RabbitPublisher{
+		queue:   queue,
+		timeout: timeout,
+		slots:   make(chan struct{}, size),
+		idle:    make(chan *publishChannel, size),
+	}
+}
+
+func (p *RabbitPublisher) Publish(ctx context.Context, msg domain.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	pc, err := p.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	routingKey := msg.RoutingKey
+	if routingKey == "" {
+		routingKey = p.queue.RoutingKey
+	}
+	messageID := msg.MessageID
+	if messageID == "" {
+		messageID = utils.NewID()
+	}
+
+	exchange := p.queue.ExchangeName
+	publishing := amqp.Publishing{
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
+		Timestamp:    time.Now(),
+		Type:         msg.Type,
+		Body:         msg.Body,
+	}
+	if msg.Delay > 0 {
+		// Routing to the work queues happens once the delay is over, so an
+		// unroutable delayed message is not reported back.
+		exchange = p.queue.DelayExchange
+		if p.queue.DelayPlugin {
+			publishing.Headers = amqp.Table{"x-delay": msg.Delay.Milliseconds()}
+		} else {
+			publishing.Expiration = strconv.FormatInt(msg.Delay.Milliseconds(), 10)
+		}
+	}
+
+	confirm, err := pc.ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, true, false, publishing)
+	if err != nil {
+		p.release(pc, false)
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	if err := awaitConfirm(ctx, confirm); err != nil {
+		// After a timeout the confirm may still arrive later; the channel
+		// cannot be reused without confusing it with the next publish.
+		p.release(pc, errors.Is(err, domain.ErrPublishNacked))
+		return err
+	}
+
+	// The broker sends basic.return before the ack of an unroutable message.
+	select {
+	case ret, ok := <-pc.returns:
+		p.release(pc, ok)
+		if !ok {
+			return nil
+		}
+		if ret.MessageId == messageID {
+			return fmt.Errorf("%w: %s", domain.ErrPublishUnroutable, ret.ReplyText)
+		}
+		logger.Warn("Discarding stale returned message ", ret.MessageId)
+	default:
+		p.release(pc, true)
+	}
+	return nil
+}
+
+func (p *RabbitPublisher) Close() {
+	for {
+		select {
+		case pc := <-p.idle:
+			if !pc.ch.IsClosed() {
+				_ = pc.ch.Close()
+			}
+			<-p.slots
+		default:
+			return
+		}
+	}
+}
+
+// acquire prefers an idle channel and opens a new one while the pool has
+// free slots.
+func (p *RabbitPublisher) acquire(ctx context.Context) (*publishChannel, error) {
+	for {
+		select {
+		case pc := <-p.idle:
+			if !pc.ch.IsClosed() {
+				return pc, nil
+			}
+			<-p.slots
+			continue
+		default:
+		}
+
+		select {
+		case pc := <-p.idle:
+			if !pc.ch.IsClosed() {
+				return pc, nil
+			}
+			<-p.slots
+		case p.slots <- struct{}{}:
+			pc, err := p.open()
+			if err != nil {
+				<-p.slots
+				return nil, err
+			}
+			return pc, nil
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: no publisher channel available", domain.ErrPublishTimeout)
+		}
+	}
+}
+
+func (p *RabbitPublisher) open() (*publishChannel, error) {
+	ch, err := p.queue.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	return &publishChannel{
+		ch:      ch,
+		returns: ch.NotifyReturn(make(chan amqp.Return, 1)),
+	}, nil
+}
+
+func (p *RabbitPublisher) release(pc *publishChannel, reusable bool) {
+	if reusable && !pc.ch.IsClosed() {
+		p.idle <- pc
+		return
+	}
+	if !pc.ch.IsClosed() {
+		_ = pc.ch.Close()
+	}
+	<-p.slots
+}
+
+// awaitConfirm waits for the broker to ack a publish made in confirm mode.
+func awaitConfirm(ctx context.Context, confirm *amqp.DeferredConfirmation) error {
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrPublishTimeout, err)
+	}
+	if !acked {
+		return domain.ErrPublishNacked
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// FailureHandler takes ownership of a message whose handler failed. A nil
+// return means the message was rerouted and can be acked.
+type FailureHandler func(ctx context.Context, msg amqp.Delivery, cause error) error
+
+// Publisher is the subset of *amqp.Channel used to reroute failed messages.
+type Publisher interface {
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+// confirmChannel publishes on a channel in confirm mode and waits for the
+// broker to ack every message, so that a failed message is only acked once
+// its rerouted copy is safely on the retry or dead-letter queue.
+type confirmChannel struct {
+	ch      *amqp.Channel
+	timeout time.Duration
+}
+
+// newConfirmChannel puts ch into confirm mode. Publishes fail with
+// domain.ErrPublishTimeout when the broker has not confirmed them within
+// timeout.
+func newConfirmChannel(ch *amqp.Channel, timeout time.Duration) (*confirmChannel, error) {
+	if timeout <= 0 {
+		timeout = defaultPublishTimeout
+	}
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	return &confirmChannel{ch: ch, timeout: timeout}, nil
+}
+
+func (c *confirmChannel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	confirm, err := c.ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, immediate, msg)
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+	return awaitConfirm(ctx, confirm)
+}
+
+// Retrier sends transient failures to the delay queue for their attempt and
+// everything else to the dead-letter exchange.
+type Retrier struct {
+	mu        sync.Mutex
+	publisher Publisher
+	queue     *config.Queue
+	queueName string
+	labeler   Labeler
+}
+
+// NewRetrier handles failures of messages consumed from queueName, labelling
+// them in metrics with labeler.
+func NewRetrier(publisher Publisher, queue *config.Queue, queueName string, labeler Labeler) *Retrier {
+	return &Retrier{publisher: publisher, queue: queue, queueName: queueName, labeler: labeler}
+}
+
+func (r *Retrier) HandleFailure(ctx context.Context, msg amqp.Delivery, cause error) error {
+	attempt := Attempts(msg) + 1
+	headers := copyHeaders(msg.Headers)
+	headers[config.HeaderAttempts] = int32(attempt)
+	headers[config.HeaderFailureReason] = cause.Error()
+	headers[config.HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+	if _, ok := headers[config.HeaderOriginalRouting]; !ok {
+		headers[config.HeaderOriginalRouting] = msg.RoutingKey
+	}
+
+	permanent := domain.IsPermanent(cause)
+	if permanent || attempt >= r.queue.Retry.MaxAttempts {
+		headers[config.HeaderPermanent] = permanent
+		logger.Warn(fmt.Sprintf("Dead-lettering message after %d attempt(s): %v", attempt, cause))
+		if err := r.publish(ctx, r.queue.DeadLetterExchange, "", msg, headers); err != nil {
+			return err
+		}
+		MessagesDeadLettered.WithLabelValues(label(r.labeler, msg)).Inc()
+		return nil
+	}
+
+	logger.Info(fmt.Sprintf("Retrying message in %s (attempt %d of %d): %v",
+		r.queue.Retry.Delay(attempt), attempt, r.queue.Retry.MaxAttempts, cause))
+	if err := r.publish(ctx, "", config.RetryQueueName(r.queueName, attempt), msg, headers); err != nil {
+		return err
+	}
+	MessagesRetried.WithLabelValues(label(r.labeler, msg)).Inc()
+	return nil
+}
+
+func (r *Retrier) publish(ctx context.Context, exchange, key string, msg amqp.Delivery, headers amqp.Table) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.publisher.PublishWithContext(ctx, exchange, key, false, false, amqp.Publishing{
+		Headers:         headers,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		CorrelationId:   msg.CorrelationId,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		AppId:           msg.AppId,
+		Body:            msg.Body,
+	})
+}
+
+// Attempts returns how many times the message has already failed.
+func Attempts(msg amqp.Delivery) int {
+	switch v := msg.Headers[config.HeaderAttempts].(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+func copyHeaders(headers amqp.Table) amqp.Table {
+	copied := make(amqp.Table, len(headers)+5)
+	for k, v := range headers {
+		copied[k] = v
+	}
+	return copied
+}
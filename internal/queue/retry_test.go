@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type published struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+// fakePublisher records every publishing
+type fakePublisher struct {
+	published []published
+	err       error
+}
+
+func (f *fakePublisher) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.published = append(f.published, published{exchange: exchange, key: key, msg: msg})
+	return nil
+}
+
+func testQueue() *config.Queue {
+	return &config.Queue{
+		QueueName:          "todo_queue",
+		DeadLetterExchange: "todo_exchange.dlx",
+		Retry: config.RetryPolicy{
+			MaxAttempts:  3,
+			InitialDelay: time.Second,
+			MaxDelay:     time.Minute,
+			Multiplier:   2,
+		},
+	}
+}
+
+func TestRetrier_HandleFailure(t *testing.T) {
+	tests := []struct {
+		name         string
+		attempts     interface{}
+		cause        error
+		wantExchange string
+		wantKey      string
+		wantAttempts int32
+	}{
+		{
+			name:         "first transient failure goes to the first retry queue",
+			cause:        errors.New("database error"),
+			wantExchange: "",
+			wantKey:      "todo_queue.retry.1",
+			wantAttempts: 1,
+		},
+		{
+			name:         "second transient failure goes to the second retry queue",
+			attempts:     int32(1),
+			cause:        errors.New("database error"),
+			wantExchange: "",
+			wantKey:      "todo_queue.retry.2",
+			wantAttempts: 2,
+		},
+		{
+			name:         "exhausted retries are dead-lettered",
+			attempts:     int64(2),
+			cause:        errors.New("database error"),
+			wantExchange: "todo_exchange.dlx",
+			wantAttempts: 3,
+		},
+		{
+			name:         "permanent failures are dead-lettered immediately",
+			cause:        domain.Permanent(errors.New("unknown event type: foo")),
+			wantExchange: "todo_exchange.dlx",
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &fakePublisher{}
+			retrier := NewRetrier(publisher, testQueue(), "todo_queue", nil)
+
+			msg := amqp.Delivery{Body: []byte(`{}`), RoutingKey: "todo.notification", Headers: amqp.Table{}}
+			if tt.attempts != nil {
+				msg.Headers[config.HeaderAttempts] = tt.attempts
+			}
+
+			if err := retrier.HandleFailure(context.Background(), msg, tt.cause); err != nil {
+				t.Fatalf("Retrier.HandleFailure() error = %v", err)
+			}
+			if len(publisher.published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(publisher.published))
+			}
+
+			got := publisher.published[0]
+			if got.exchange != tt.wantExchange || got.key != tt.wantKey {
+				t.Errorf("published to %q/%q, want %q/%q", got.exchange, got.key, tt.wantExchange, tt.wantKey)
+			}
+			if attempts := got.msg.Headers[config.HeaderAttempts]; attempts != tt.wantAttempts {
+				t.Errorf("attempts header = %v, want %v", attempts, tt.wantAttempts)
+			}
+			if reason := got.msg.Headers[config.HeaderFailureReason]; reason != tt.cause.Error() {
+				t.Errorf("failure reason header = %v, want %v", reason, tt.cause.Error())
+			}
+			if key := got.msg.Headers[config.HeaderOriginalRouting]; key != "todo.notification" {
+				t.Errorf("original routing key header = %v, want todo.notification", key)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := config.RetryPolicy{MaxAttempts: 10, InitialDelay: time.Second, MaxDelay: 10 * time.Second, Multiplier: 2}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second}
+	for i, w := range want {
+		if got := policy.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
+
+func TestPool_ReroutesFailedMessages(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	source := newFakeSource(ack, 2)
+	close(source.msgs)
+
+	handler := func(ctx context.Context, msg amqp.Delivery) error {
+		return errors.New("boom")
+	}
+
+	pool := NewPool(1, handler, time.Second)
+	pool.SetFailureHandler(func(ctx context.Context, msg amqp.Delivery, cause error) error {
+		if msg.DeliveryTag == 2 {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	})
+	_ = pool.Run(context.Background(), source)
+
+	if acked, nacked := ack.counts(); acked != 1 || nacked != 1 {
+		t.Errorf("acked = %d, nacked = %d, want 1 and 1", acked, nacked)
+	}
+}
@@ -2,50 +2,245 @@ package queue
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
 	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/events"
 	"github.com/nayeem-bd/Todo-App/internal/logger"
+	"github.com/nayeem-bd/Todo-App/internal/outbox"
 	"github.com/nayeem-bd/Todo-App/internal/store"
 	queue2 "github.com/nayeem-bd/Todo-App/modules/todo/delivery/queue"
 	"github.com/nayeem-bd/Todo-App/modules/todo/usecase"
-	"gorm.io/gorm"
-	"os"
-	"os/signal"
-	"syscall"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	shutdownTimeout = 30 * time.Second
+	pruneInterval   = time.Hour
 )
 
-func Work(db *gorm.DB, cache *config.Cache, queue *config.Queue) {
-	todoUsecase := usecase.NewTodoUsecase(store.New(db), cache, queue)
-	todoWorker := queue2.NewTodoWorker(todoUsecase)
+// Work consumes every bound queue until ctx is cancelled. The caller owns
+// broker and closes it afterwards. Worker concurrency, prefetch and the
+// cache TTL follow watcher's reloads.
+func Work(ctx context.Context, s store.Store, cache domain.Cache, broker Broker, cfg *config.Config, watcher *config.Watcher) {
+	queue := broker.Queue()
+	outboxConfig, schedulerConfig, workerConfig := cfg.Outbox, cfg.Scheduler, cfg.Worker
+	todoUsecase := usecase.NewTodoUsecase(s, cache)
+	todoUsecase.SetCacheTTL(time.Duration(cfg.Cache.TodosTTL) * time.Second)
 
-	ch, err := queue.Conn.Channel()
-	if err != nil {
-		panic("Failed to open a channel: " + err.Error())
+	registry := events.NewRegistry()
+	registry.Use(
+		events.Recover(),
+		events.Trace(),
+		events.Logging(),
+		events.Metrics(),
+		events.Timeout(queue.HandlerTimeout),
+	)
+	queue2.NewTodoWorker(todoUsecase).Register(registry)
+
+	hostname, _ := os.Hostname()
+
+	var relayDone chan struct{}
+	if outboxConfig.RelayInWorker {
+		relay := NewOutboxRelay(s, broker.Publisher(), outboxConfig)
+		relayDone = make(chan struct{})
+		go func() {
+			defer close(relayDone)
+			relay.Run(ctx)
+		}()
+	}
+
+	if schedulerConfig.Horizon > 0 && cfg.Queue.Driver == config.QueueDriverRabbitMQ && !cfg.RabbitMQ.DelayedMessagePlugin {
+		logger.Warn("scheduler.horizon is set without rabbitmq.delayed_message_plugin; " +
+			"messages with different delays share one TTL queue and may be delivered late")
+	}
+
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		NewScheduler(s, broker.Publisher(), schedulerConfig).Run(ctx)
+	}()
+
+	if queue.ProcessedRetention > 0 {
+		go pruneProcessed(ctx, s.ProcessedMessageRepository(), queue.ProcessedRetention)
+	}
+
+	if workerConfig.DepthInterval > 0 {
+		go sampleDepth(ctx, broker, time.Duration(workerConfig.DepthInterval)*time.Second)
+	}
+
+	// Every bound queue gets its own pool and consumer, all dispatching
+	// through the same registry.
+	var wg sync.WaitGroup
+	var pools []*Pool
+	for _, binding := range queue.Bindings {
+		pool := NewPool(queue.WorkerPoolCount, registry.Dispatch, shutdownTimeout)
+		pool.SetLabeler(registry.Label)
+		pool.SetPrefetch(queue.PrefetchCount)
+		pools = append(pools, pool)
+		consumerTag := fmt.Sprintf("todo-worker-%s-%d-%s", hostname, os.Getpid(), binding.Queue)
+
+		wg.Add(1)
+		go func(queueName string) {
+			defer wg.Done()
+			consumeLoop(ctx, broker, queueName, pool, consumerTag)
+		}(binding.Queue)
+	}
+
+	watcher.OnChange(func(cfg *config.Config) {
+		todoUsecase.SetCacheTTL(time.Duration(cfg.Cache.TodosTTL) * time.Second)
+		for _, pool := range pools {
+			pool.SetPrefetch(cfg.RabbitMQ.PrefetchCount)
+			pool.Resize(cfg.RabbitMQ.WorkerPoolCount)
+		}
+	})
+	wg.Wait()
+
+	if relayDone != nil {
+		<-relayDone
+	}
+	<-schedulerDone
+	logger.Info("Worker gracefully stopped")
+}
+
+// consumeLoop consumes queueName until ctx is cancelled, registering the
+// consumer again whenever the connection comes back.
+func consumeLoop(ctx context.Context, broker Broker, queueName string, pool *Pool, consumerTag string) {
+	logger.Info(fmt.Sprintf(" [*] Waiting for messages on %s with %d workers. To exit press CTRL+C", queueName, pool.Size()))
+	for {
+		err := broker.Consume(ctx, queueName, pool, consumerTag)
+		if err == nil || ctx.Err() != nil {
+			if err != nil {
+				logger.Error("Consumer for " + queueName + " stopped: " + err.Error())
+			}
+			return
+		}
+
+		// The connection manager is already reconnecting; register the
+		// consumer again once it is back.
+		logger.Warn("Consumer for " + queueName + " lost, waiting for RabbitMQ: " + err.Error())
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+		if err := broker.WaitConnected(ctx); err != nil {
+			return
+		}
+		logger.Info("Re-registering consumer for " + queueName)
 	}
+}
 
-	defer ch.Close()
+// consume runs one consumer session on fresh channels and returns when ctx
+// is cancelled or the channels are lost.
+func consume(ctx context.Context, queue *config.Queue, queueName string, pool *Pool, consumerTag string) error {
+	ch, err := queue.Channel()
+	if err != nil {
+		return err
+	}
+	defer closeChannel(ch)
 
-	msgs, err := ch.Consume(queue.QueueName, "", false, false, false, false, nil)
+	// Retries are published on their own channel so they never interleave
+	// with the consumer's acks. The failed message is acked only after the
+	// broker confirmed its reroute, and requeued otherwise.
+	retryCh, err := queue.Channel()
 	if err != nil {
-		panic("Failed to register a consumer: " + err.Error())
+		return err
 	}
+	defer closeChannel(retryCh)
+	retryPublisher, err := newConfirmChannel(retryCh, queue.PublishTimeout)
+	if err != nil {
+		return err
+	}
+	pool.SetFailureHandler(NewRetrier(retryPublisher, queue, queueName, pool.Label).HandleFailure)
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
-	go func() {
-		for msg := range msgs {
-			if err := todoWorker.ProcessMessage(ctx, msg); err != nil {
-				logger.Error("Failed to process message: " + err.Error())
-				if err := msg.Nack(false, true); err != nil {
-					logger.Error("Failed to nack message: " + err.Error())
-				}
+	pool.SetQoS(func(prefetch int) error { return ch.Qos(prefetch, 0, false) })
+	defer pool.SetQoS(nil)
+
+	return pool.Run(ctx, &amqpSource{
+		ch:          ch,
+		queueName:   queueName,
+		consumerTag: consumerTag,
+	})
+}
+
+func closeChannel(ch *amqp.Channel) {
+	if ch.IsClosed() {
+		return
+	}
+	if err := ch.Close(); err != nil {
+		logger.Error("Failed to close channel: " + err.Error())
+	}
+}
+
+func NewOutboxRelay(s store.Store, publisher domain.EventPublisher, outboxConfig config.OutboxConfig) *outbox.Relay {
+	return outbox.NewRelay(
+		s,
+		publisher,
+		time.Duration(outboxConfig.PollInterval)*time.Second,
+		outboxConfig.BatchSize,
+		time.Duration(outboxConfig.MaxBackoff)*time.Second,
+		outboxConfig.MaxAttempts,
+		time.Duration(outboxConfig.MaxPassDuration)*time.Second,
+	)
+}
+
+// pruneProcessed forgets processed message IDs once they are older than
+// retention, by which time the broker will no longer redeliver them.
+func pruneProcessed(ctx context.Context, repo domain.ProcessedMessageRepository, retention time.Duration) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := repo.DeleteBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("Failed to prune processed messages: " + err.Error())
+		} else if deleted > 0 {
+			logger.Info(fmt.Sprintf("Pruned %d processed messages", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sampleDepth records how far behind the consumers are as the number of
+// messages waiting on each bound queue.
+func sampleDepth(ctx context.Context, broker Broker, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, binding := range broker.Queue().Bindings {
+			depth, err := broker.Depth(ctx, binding.Queue)
+			if err != nil {
+				logger.Debug("Failed to sample queue depth: " + err.Error())
 				continue
 			}
-			if err := msg.Ack(false); err != nil {
-				logger.Error("Failed to ack message: " + err.Error())
-			}
+			QueueDepth.WithLabelValues(binding.Queue).Set(float64(depth))
 		}
-	}()
-	logger.Info(" [*] Waiting for messages. To exit press CTRL+C")
-	<-ctx.Done()
-	logger.Info("Shutting down worker gracefully...")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewScheduler(s store.Store, publisher domain.EventPublisher, schedulerConfig config.SchedulerConfig) *outbox.Scheduler {
+	return outbox.NewScheduler(
+		s,
+		publisher,
+		time.Duration(schedulerConfig.PollInterval)*time.Second,
+		schedulerConfig.BatchSize,
+		time.Duration(schedulerConfig.Horizon)*time.Second,
+		time.Duration(schedulerConfig.MaxBackoff)*time.Second,
+	)
 }
@@ -0,0 +1,28 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileProvider reads secrets from files, such as Docker and Kubernetes secret
+// mounts. Relative paths are resolved against Dir.
+type FileProvider struct {
+	Dir string
+}
+
+func (p FileProvider) Secret(_ context.Context, ref string) (string, error) {
+	path := ref
+	if !filepath.IsAbs(path) && p.Dir != "" {
+		path = filepath.Join(p.Dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	// Secret files usually end with a newline that is not part of the value
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,120 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/internal/logger"
+)
+
+const (
+	ProviderFile  = "file"
+	ProviderVault = "vault"
+)
+
+var ErrUnknownProvider = errors.New("unknown secret provider")
+
+// Provider looks up one secret by a provider specific reference, such as a
+// file path or a Vault path and key.
+type Provider interface {
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+var reference = regexp.MustCompile(`\$\{secret:([a-z]+):([^}]+)\}`)
+
+// Ref builds the ${secret:<provider>:<ref>} reference Expand resolves.
+func Ref(provider, ref string) string {
+	return "${secret:" + provider + ":" + ref + "}"
+}
+
+func HasReference(value string) bool {
+	return reference.MatchString(value)
+}
+
+// Resolver expands secret references through the registered providers.
+// Secrets are cached for ttl, so a rotated secret is picked up by the first
+// lookup after that; when a refresh fails the cached value is kept.
+type Resolver struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	providers map[string]Provider
+	cache     map[string]cachedSecret
+}
+
+type cachedSecret struct {
+	value  string
+	readAt time.Time
+}
+
+func NewResolver(ttl time.Duration) *Resolver {
+	return &Resolver{
+		ttl:       ttl,
+		now:       time.Now,
+		providers: make(map[string]Provider),
+		cache:     make(map[string]cachedSecret),
+	}
+}
+
+func (r *Resolver) Register(name string, provider Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[name] = provider
+}
+
+// Expand replaces every reference in value with its secret. Values without
+// references are returned unchanged.
+func (r *Resolver) Expand(ctx context.Context, value string) (string, error) {
+	var expandErr error
+	expanded := reference.ReplaceAllStringFunc(value, func(match string) string {
+		if expandErr != nil {
+			return match
+		}
+		parts := reference.FindStringSubmatch(match)
+		secret, err := r.lookup(ctx, parts[1], parts[2])
+		if err != nil {
+			expandErr = fmt.Errorf("failed to resolve secret %s: %w", match, err)
+			return match
+		}
+		return secret
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return expanded, nil
+}
+
+func (r *Resolver) lookup(ctx context.Context, name, ref string) (string, error) {
+	key := name + ":" + ref
+
+	r.mu.Lock()
+	cached, ok := r.cache[key]
+	provider := r.providers[name]
+	r.mu.Unlock()
+
+	if ok && r.now().Sub(cached.readAt) < r.ttl {
+		return cached.value, nil
+	}
+	if provider == nil {
+		return "", fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+
+	value, err := provider.Secret(ctx, ref)
+	if err != nil {
+		if ok {
+			logger.Warn(fmt.Sprintf("Failed to refresh secret %s, keeping the cached value: %v", Ref(name, ref), err))
+			return cached.value, nil
+		}
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.cache[key] = cachedSecret{value: value, readAt: r.now()}
+	r.mu.Unlock()
+	return value, nil
+}
@@ -0,0 +1,244 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestVaultAddrEnv and TestVaultTokenEnv point TestVaultProvider_DevServer at
+// a Vault dev server, e.g. `vault server -dev -dev-root-token-id=root`.
+const (
+	TestVaultAddrEnv  = "TEST_VAULT_ADDR"
+	TestVaultTokenEnv = "TEST_VAULT_TOKEN"
+)
+
+type mockProvider struct {
+	values map[string]string
+	err    error
+	calls  int
+}
+
+func (m *mockProvider) Secret(_ context.Context, ref string) (string, error) {
+	m.calls++
+	if m.err != nil {
+		return "", m.err
+	}
+	value, ok := m.values[ref]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return value, nil
+}
+
+func TestResolver_Expand(t *testing.T) {
+	provider := &mockProvider{values: map[string]string{"user": "root", "password": "s3cret"}}
+	resolver := NewResolver(time.Minute)
+	resolver.Register("mock", provider)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr error
+	}{
+		{name: "plain value", value: "localhost", want: "localhost"},
+		{name: "whole value", value: "${secret:mock:password}", want: "s3cret"},
+		{
+			name:  "embedded references",
+			value: "user=${secret:mock:user} password=${secret:mock:password} dbname=todoapp",
+			want:  "user=root password=s3cret dbname=todoapp",
+		},
+		{name: "unknown provider", value: "${secret:nope:password}", wantErr: ErrUnknownProvider},
+		{name: "missing secret", value: "${secret:mock:missing}", wantErr: errors.New("not found")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.Expand(context.Background(), tt.value)
+			if tt.wantErr != nil {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr.Error()) {
+					t.Fatalf("Expand() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolver_Rotation(t *testing.T) {
+	provider := &mockProvider{values: map[string]string{"password": "old"}}
+	now := time.Now()
+	resolver := NewResolver(time.Minute)
+	resolver.now = func() time.Time { return now }
+	resolver.Register("mock", provider)
+
+	expand := func() string {
+		t.Helper()
+		value, err := resolver.Expand(context.Background(), "${secret:mock:password}")
+		if err != nil {
+			t.Fatalf("Expand() error = %v", err)
+		}
+		return value
+	}
+
+	if got := expand(); got != "old" {
+		t.Fatalf("Expand() = %q, want old", got)
+	}
+	provider.values["password"] = "new"
+	if got := expand(); got != "old" || provider.calls != 1 {
+		t.Errorf("Expand() within the refresh interval = %q after %d reads, want the cached value", got, provider.calls)
+	}
+
+	now = now.Add(time.Minute)
+	if got := expand(); got != "new" {
+		t.Errorf("Expand() after the refresh interval = %q, want the rotated value", got)
+	}
+
+	now = now.Add(time.Minute)
+	provider.err = errors.New("vault sealed")
+	if got := expand(); got != "new" {
+		t.Errorf("Expand() with a failing provider = %q, want the cached value", got)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "db_password"), []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		provider FileProvider
+		ref      string
+		want     string
+		wantErr  bool
+	}{
+		{name: "absolute path", ref: filepath.Join(dir, "db_password"), want: "s3cret"},
+		{name: "relative to dir", provider: FileProvider{Dir: dir}, ref: "db_password", want: "s3cret"},
+		{name: "missing file", provider: FileProvider{Dir: dir}, ref: "missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.provider.Secret(context.Background(), tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Secret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Secret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/todoapp":
+			_, _ = w.Write([]byte(`{"data":{"data":{"db_password":"v2-secret"},"metadata":{"version":3}}}`))
+		case "/v1/kv/todoapp":
+			_, _ = w.Write([]byte(`{"data":{"db_password":"v1-secret","port":5432}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		token   string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{name: "kv version 2", token: "root", ref: "secret/data/todoapp#db_password", want: "v2-secret"},
+		{name: "kv version 1", token: "root", ref: "kv/todoapp#db_password", want: "v1-secret"},
+		{name: "non-string value", token: "root", ref: "kv/todoapp#port", want: "5432"},
+		{name: "missing key", token: "root", ref: "kv/todoapp#password", wantErr: `no key "password"`},
+		{name: "missing path", token: "root", ref: "secret/data/other#password", wantErr: "404"},
+		{name: "bad token", token: "guess", ref: "kv/todoapp#db_password", wantErr: "permission denied"},
+		{name: "no key", token: "root", ref: "kv/todoapp", wantErr: "invalid vault reference"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewVaultProvider(server.URL+"/", StaticToken(tt.token), time.Second)
+			got, err := provider.Secret(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Secret() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Secret() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Secret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestVaultProvider_DevServer writes a secret to a real Vault's default KV
+// version 2 mount at secret/ and reads it back, before and after rotating it.
+func TestVaultProvider_DevServer(t *testing.T) {
+	address, token := os.Getenv(TestVaultAddrEnv), os.Getenv(TestVaultTokenEnv)
+	if address == "" || token == "" {
+		t.Skipf("set %s and %s to run against a Vault dev server", TestVaultAddrEnv, TestVaultTokenEnv)
+	}
+
+	write := func(password string) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"data": map[string]string{"db_password": password}})
+		req, err := http.NewRequest(http.MethodPost, strings.TrimRight(address, "/")+"/v1/secret/data/todoapp-test", strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Vault-Token", token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to write to vault: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("vault returned %s writing the test secret", resp.Status)
+		}
+	}
+
+	resolver := NewResolver(0)
+	resolver.Register(ProviderVault, NewVaultProvider(address, StaticToken(token), 5*time.Second))
+	ref := Ref(ProviderVault, "secret/data/todoapp-test#db_password")
+
+	for _, password := range []string{"first", "rotated"} {
+		write(password)
+		got, err := resolver.Expand(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("Expand() error = %v", err)
+		}
+		if got != password {
+			t.Errorf("Expand() = %q, want %q", got, password)
+		}
+	}
+}
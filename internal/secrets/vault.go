@@ -0,0 +1,88 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultProvider reads secrets from a HashiCorp Vault KV engine. References
+// take the form <path>#<key>, e.g. secret/data/todoapp#db_password for KV
+// version 2 or secret/todoapp#db_password for version 1.
+type VaultProvider struct {
+	address string
+	token   TokenFunc
+	client  *http.Client
+}
+
+// TokenFunc returns the Vault token for the next request, so that a rotated
+// token is used without building a new provider.
+type TokenFunc func(ctx context.Context) (string, error)
+
+// StaticToken always returns token.
+func StaticToken(token string) TokenFunc {
+	return func(context.Context) (string, error) { return token, nil }
+}
+
+func NewVaultProvider(address string, token TokenFunc, timeout time.Duration) *VaultProvider {
+	return &VaultProvider{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type vaultResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []string       `json:"errors"`
+}
+
+func (p *VaultProvider) Secret(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("invalid vault reference %q, want <path>#<key>", ref)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.address+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build vault request: %w", err)
+	}
+	token, err := p.token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the vault token: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s for %s: %s", resp.Status, path, strings.Join(body.Errors, "; "))
+	}
+
+	data := body.Data
+	// KV version 2 nests the secret under data.data next to data.metadata
+	if nested, ok := data["data"].(map[string]any); ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = nested
+		}
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %q", path, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
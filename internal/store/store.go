@@ -1,27 +1,75 @@
 package store
 
 import (
+	"context"
+
 	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/database"
+	outboxRepo "github.com/nayeem-bd/Todo-App/modules/outbox/repository"
+	processedRepo "github.com/nayeem-bd/Todo-App/modules/processed/repository"
+	scheduledRepo "github.com/nayeem-bd/Todo-App/modules/scheduled/repository"
 	todoRepo "github.com/nayeem-bd/Todo-App/modules/todo/repository"
 	"gorm.io/gorm"
 )
 
 type Store interface {
 	TodoRepository() domain.TodoRepository
+	OutboxRepository() domain.OutboxRepository
+	ProcessedMessageRepository() domain.ProcessedMessageRepository
+	ScheduledMessageRepository() domain.ScheduledMessageRepository
+	// WithTx runs fn with a Store whose repositories share one transaction,
+	// committed when fn returns nil and rolled back otherwise. Calling WithTx
+	// on that Store opens a savepoint instead, so a failing inner unit rolls
+	// back only its own writes and the outer fn decides whether to go on.
+	WithTx(ctx context.Context, fn func(Store) error) error
 }
 
 type DataStore struct {
-	db       *gorm.DB
-	TodoRepo domain.TodoRepository
+	cluster       *database.Cluster
+	timeouts      database.Timeouts
+	TodoRepo      domain.TodoRepository
+	OutboxRepo    domain.OutboxRepository
+	ProcessedRepo domain.ProcessedMessageRepository
+	ScheduledRepo domain.ScheduledMessageRepository
 }
 
-func New(db *gorm.DB) Store {
+// New builds repositories that bound every operation by timeouts and read
+// from the cluster's replicas where they can.
+func New(cluster *database.Cluster, timeouts database.Timeouts) Store {
+	db := cluster.Primary()
 	return &DataStore{
-		db:       db,
-		TodoRepo: todoRepo.NewTodoRepository(db),
+		cluster:       cluster,
+		timeouts:      timeouts,
+		TodoRepo:      todoRepo.NewTodoRepository(cluster, timeouts),
+		OutboxRepo:    outboxRepo.NewOutboxRepository(db, timeouts),
+		ProcessedRepo: processedRepo.NewProcessedMessageRepository(db, timeouts),
+		ScheduledRepo: scheduledRepo.NewScheduledMessageRepository(db, timeouts),
 	}
 }
 
 func (d DataStore) TodoRepository() domain.TodoRepository {
 	return d.TodoRepo
 }
+
+func (d DataStore) OutboxRepository() domain.OutboxRepository {
+	return d.OutboxRepo
+}
+
+func (d DataStore) ProcessedMessageRepository() domain.ProcessedMessageRepository {
+	return d.ProcessedRepo
+}
+
+func (d DataStore) ScheduledMessageRepository() domain.ScheduledMessageRepository {
+	return d.ScheduledRepo
+}
+
+// WithTx relies on gorm.DB.Transaction, which uses SAVEPOINT and ROLLBACK TO
+// SAVEPOINT when d is already bound to a transaction. Every read inside the
+// transaction goes through tx, and later reads of the same request session go
+// to the primary so they see what the transaction wrote.
+func (d DataStore) WithTx(ctx context.Context, fn func(Store) error) error {
+	database.MarkWrite(ctx)
+	return d.cluster.Primary().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(New(database.NewCluster(tx), d.timeouts))
+	})
+}
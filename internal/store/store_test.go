@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/database"
+	"github.com/nayeem-bd/Todo-App/internal/migrations"
+)
+
+func TestDataStore_WithTx(t *testing.T) {
+	errInner := errors.New("inner failed")
+	errOuter := errors.New("outer failed")
+
+	tests := []struct {
+		name      string
+		innerErr  error
+		outerErr  error
+		wantTodos []string
+	}{
+		{name: "commits both", wantTodos: []string{"outer", "inner"}},
+		{name: "rolls back only the failed savepoint", innerErr: errInner, wantTodos: []string{"outer"}},
+		{name: "rolls back everything when the outer fn fails", outerErr: errOuter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSQLiteStore(t)
+			ctx := context.Background()
+
+			err := s.WithTx(ctx, func(tx Store) error {
+				if _, err := tx.TodoRepository().Create(ctx, &domain.Todo{Title: "outer", Description: "d"}); err != nil {
+					return err
+				}
+				err := tx.WithTx(ctx, func(inner Store) error {
+					if _, err := inner.TodoRepository().Create(ctx, &domain.Todo{Title: "inner", Description: "d"}); err != nil {
+						return err
+					}
+					return tt.innerErr
+				})
+				if !errors.Is(err, tt.innerErr) {
+					t.Errorf("inner WithTx() error = %v, want %v", err, tt.innerErr)
+				}
+				return tt.outerErr
+			})
+			if !errors.Is(err, tt.outerErr) {
+				t.Fatalf("WithTx() error = %v, want %v", err, tt.outerErr)
+			}
+
+			todos, err := s.TodoRepository().GetAll(ctx)
+			if err != nil {
+				t.Fatalf("GetAll() error = %v", err)
+			}
+			if len(todos) != len(tt.wantTodos) {
+				t.Fatalf("GetAll() = %d todos, want %v", len(todos), tt.wantTodos)
+			}
+			for i, title := range tt.wantTodos {
+				if todos[i].Title != title {
+					t.Errorf("todo %d = %q, want %q", i, todos[i].Title, title)
+				}
+			}
+		})
+	}
+}
+
+func newSQLiteStore(t *testing.T) Store {
+	t.Helper()
+
+	db, err := config.ConnectDatabase(config.DatabaseConfig{
+		Driver:            config.DatabaseDriverSQLite,
+		Path:              filepath.Join(t.TempDir(), "todoapp.db"),
+		MaxOpenConnection: 2,
+	})
+	if err != nil {
+		t.Fatalf("ConnectDatabase() error = %v", err)
+	}
+	embedded, err := migrations.Embedded(db.Dialector.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.New(db, embedded).Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return New(database.NewCluster(db), database.Timeouts{Read: 5 * time.Second, Write: 5 * time.Second})
+}
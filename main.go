@@ -9,7 +9,7 @@ import (
 func main() {
 	args := os.Args
 	if len(args) < 2 {
-		fmt.Println("Usage: go run main.go serve")
+		fmt.Println("Usage: go run main.go serve|work|all|relay|dlq|migrate|config")
 		return
 	}
 	if args[1] == "serve" {
@@ -19,4 +19,24 @@ func main() {
 	if args[1] == "work" {
 		cmd.Work()
 	}
+
+	if args[1] == "all" {
+		cmd.All()
+	}
+
+	if args[1] == "relay" {
+		cmd.Relay()
+	}
+
+	if args[1] == "dlq" {
+		cmd.DLQ(args[2:])
+	}
+
+	if args[1] == "migrate" {
+		cmd.Migrate(args[2:])
+	}
+
+	if args[1] == "config" {
+		cmd.ConfigCommand(args[2:])
+	}
 }
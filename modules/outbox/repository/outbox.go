@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository struct {
+	db       *gorm.DB
+	timeouts database.Timeouts
+}
+
+func NewOutboxRepository(db *gorm.DB, timeouts database.Timeouts) *OutboxRepository {
+	return &OutboxRepository{db: db, timeouts: timeouts}
+}
+
+func (r *OutboxRepository) Add(ctx context.Context, event *domain.OutboxEvent) (*domain.OutboxEvent, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Write)
+	defer cancel()
+
+	if event.AvailableAt.IsZero() {
+		event.AvailableAt = time.Now()
+	}
+	if err := r.db.WithContext(ctx).Create(event).Error; err != nil {
+		return nil, database.Error(ctx, err)
+	}
+	return event, nil
+}
+
+func (r *OutboxRepository) FetchPending(ctx context.Context, limit int) ([]*domain.OutboxEvent, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Read)
+	defer cancel()
+
+	var events []*domain.OutboxEvent
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("sent_at IS NULL AND failed_at IS NULL AND available_at <= ?", time.Now()).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, database.Error(ctx, err)
+	}
+	return events, nil
+}
+
+func (r *OutboxRepository) MarkSent(ctx context.Context, id int64) error {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Write)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).
+		Model(&domain.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"sent_at": time.Now(), "last_error": ""}).Error
+	return database.Error(ctx, err)
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int64, lastError string, availableAt time.Time) error {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Write)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).
+		Model(&domain.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":     gorm.Expr("attempts + 1"),
+			"last_error":   lastError,
+			"available_at": availableAt,
+		}).Error
+	return database.Error(ctx, err)
+}
+
+func (r *OutboxRepository) MarkParked(ctx context.Context, id int64, lastError string) error {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Write)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).
+		Model(&domain.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": lastError,
+			"failed_at":  time.Now(),
+		}).Error
+	return database.Error(ctx, err)
+}
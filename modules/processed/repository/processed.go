@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProcessedMessageRepository struct {
+	db       *gorm.DB
+	timeouts database.Timeouts
+}
+
+func NewProcessedMessageRepository(db *gorm.DB, timeouts database.Timeouts) *ProcessedMessageRepository {
+	return &ProcessedMessageRepository{db: db, timeouts: timeouts}
+}
+
+// MarkProcessed relies on the primary key: a concurrent insert of the same
+// message waits for the other transaction and then inserts nothing.
+func (r *ProcessedMessageRepository) MarkProcessed(ctx context.Context, consumer, messageID string) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Write)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&domain.ProcessedMessage{
+			Consumer:    consumer,
+			MessageID:   messageID,
+			ProcessedAt: time.Now(),
+		})
+	if result.Error != nil {
+		return false, database.Error(ctx, result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *ProcessedMessageRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Write)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).
+		Where("processed_at < ?", before).
+		Delete(&domain.ProcessedMessage{})
+	return result.RowsAffected, database.Error(ctx, result.Error)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScheduledMessageRepository struct {
+	db       *gorm.DB
+	timeouts database.Timeouts
+}
+
+func NewScheduledMessageRepository(db *gorm.DB, timeouts database.Timeouts) *ScheduledMessageRepository {
+	return &ScheduledMessageRepository{db: db, timeouts: timeouts}
+}
+
+func (r *ScheduledMessageRepository) Add(ctx context.Context, msg *domain.ScheduledMessage) (*domain.ScheduledMessage, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Write)
+	defer cancel()
+
+	if msg.AvailableAt.IsZero() {
+		msg.AvailableAt = time.Now()
+	}
+	if err := r.db.WithContext(ctx).Create(msg).Error; err != nil {
+		return nil, database.Error(ctx, err)
+	}
+	return msg, nil
+}
+
+// FetchDue skips rows locked by another scheduler, so several workers can
+// poll the table at once.
+func (r *ScheduledMessageRepository) FetchDue(ctx context.Context, before time.Time, limit int) ([]*domain.ScheduledMessage, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Read)
+	defer cancel()
+
+	var msgs []*domain.ScheduledMessage
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("sent_at IS NULL AND due_at <= ? AND available_at <= ?", before, time.Now()).
+		Order("due_at").
+		Limit(limit).
+		Find(&msgs).Error
+	if err != nil {
+		return nil, database.Error(ctx, err)
+	}
+	return msgs, nil
+}
+
+func (r *ScheduledMessageRepository) MarkSent(ctx context.Context, id int64) error {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Write)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).
+		Model(&domain.ScheduledMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"sent_at": time.Now(), "last_error": ""}).Error
+	return database.Error(ctx, err)
+}
+
+func (r *ScheduledMessageRepository) MarkFailed(ctx context.Context, id int64, lastError string, availableAt time.Time) error {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Write)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).
+		Model(&domain.ScheduledMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":     gorm.Expr("attempts + 1"),
+			"last_error":   lastError,
+			"available_at": availableAt,
+		}).Error
+	return database.Error(ctx, err)
+}
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/nayeem-bd/Todo-App/domain"
@@ -27,7 +29,7 @@ func (todoHandler *TodoHandler) GetTodos(w http.ResponseWriter, r *http.Request)
 	todos, err := todoHandler.todoUsecase.GetAll(r.Context())
 
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch todos", err.Error())
+		writeUsecaseError(w, "Failed to fetch todos", err)
 		return
 	}
 
@@ -51,7 +53,7 @@ func (todoHandler *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Reques
 
 	createdTodo, err := todoHandler.todoUsecase.Create(r.Context(), todo)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Failed to create todo", err.Error())
+		writeUsecaseError(w, "Failed to create todo", err)
 		return
 	}
 
@@ -73,12 +75,7 @@ func (todoHandler *TodoHandler) GetTodoByID(w http.ResponseWriter, r *http.Reque
 
 	todo, err := todoHandler.todoUsecase.GetByID(r.Context(), todoID)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch todo", err.Error())
-		return
-	}
-
-	if todo == nil {
-		utils.WriteError(w, http.StatusNotFound, "Todo not found", nil)
+		writeUsecaseError(w, "Failed to fetch todo", err)
 		return
 	}
 
@@ -98,11 +95,78 @@ func (todoHandler *TodoHandler) CompleteTodo(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = todoHandler.todoUsecase.Complete(r.Context(), todoID)
+	wait := false
+	if waitStr := r.URL.Query().Get("wait"); waitStr != "" {
+		if wait, err = strconv.ParseBool(waitStr); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid wait parameter", nil)
+			return
+		}
+	}
+
+	todo, err := todoHandler.todoUsecase.Complete(r.Context(), todoID, wait)
+	if errors.Is(err, domain.ErrCompletionPending) {
+		utils.WriteSuccess(w, http.StatusAccepted, "Todo completion is still pending", nil)
+		return
+	}
+	if err != nil {
+		writeUsecaseError(w, "Failed to complete todo", err)
+		return
+	}
+
+	// Without wait the worker may not have applied the change yet
+	if todo.DoneAt == nil {
+		utils.WriteSuccess(w, http.StatusAccepted, "Todo completion accepted", todo)
+		return
+	}
+	utils.WriteSuccess(w, http.StatusOK, "Todo completed successfully", todo)
+}
+
+func (todoHandler *TodoHandler) SnoozeTodo(w http.ResponseWriter, r *http.Request) {
+	todoID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		utils.WriteError(w, http.StatusUnprocessableEntity, "Failed to complete todo", err.Error())
+		utils.WriteError(w, http.StatusBadRequest, "Invalid todo ID format", nil)
 		return
 	}
 
-	utils.WriteSuccess(w, http.StatusOK, "Todo completed successfully", nil)
+	var req dto.SnoozeTodoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if validationErrors := todoHandler.validator.Validate(&req); len(validationErrors) > 0 {
+		utils.WriteError(w, http.StatusBadRequest, "Validation failed", validationErrors)
+		return
+	}
+	if !req.Until.After(time.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, "Validation failed", map[string]string{"until": "until must be in the future"})
+		return
+	}
+
+	todo, err := todoHandler.todoUsecase.Snooze(r.Context(), todoID, req.Until)
+	if errors.Is(err, domain.ErrTodoCompleted) {
+		utils.WriteError(w, http.StatusConflict, "Failed to snooze todo", err.Error())
+		return
+	}
+	if err != nil {
+		writeUsecaseError(w, "Failed to snooze todo", err)
+		return
+	}
+
+	utils.WriteSuccess(w, http.StatusAccepted, "Todo snoozed successfully", todo)
+}
+
+// writeUsecaseError maps repository errors to their status codes and
+// anything else to 500.
+func writeUsecaseError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		utils.WriteError(w, http.StatusNotFound, "Todo not found", nil)
+	case errors.Is(err, domain.ErrConflict):
+		utils.WriteError(w, http.StatusConflict, message, err.Error())
+	case errors.Is(err, domain.ErrTimeout):
+		utils.WriteError(w, http.StatusGatewayTimeout, message, err.Error())
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, message, err.Error())
+	}
 }
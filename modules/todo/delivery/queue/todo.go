@@ -2,11 +2,11 @@ package queue
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+
 	"github.com/nayeem-bd/Todo-App/domain"
 	"github.com/nayeem-bd/Todo-App/domain/dto"
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/nayeem-bd/Todo-App/internal/events"
 )
 
 type TodoWorker struct {
@@ -19,23 +19,28 @@ func NewTodoWorker(todoUsecase domain.TodoUsecase) *TodoWorker {
 	}
 }
 
-func (w *TodoWorker) ProcessMessage(ctx context.Context, message amqp.Delivery) error {
-	var event dto.Event
-	if err := json.Unmarshal(message.Body, &event); err != nil {
-		return fmt.Errorf("failed to decode message: %w", err)
+// Register adds the todo event handlers to r.
+func (w *TodoWorker) Register(r *events.Registry) {
+	r.Register(dto.EventTodoCompleted, events.Typed(dto.TodoCompletedDataVersion, w.TodoCompleted))
+	r.Register(dto.EventTodoSnoozeEnded, events.Typed(dto.TodoSnoozeEndedDataVersion, w.TodoSnoozeEnded))
+}
+
+func (w *TodoWorker) TodoCompleted(ctx context.Context, msg *events.Message, data dto.TodoCompletedData) error {
+	if data.TodoID == 0 {
+		return domain.Permanent(fmt.Errorf("todo ID is required for %s event", msg.Event.Type))
 	}
+	if err := w.todoUsecase.CompleteTodo(ctx, msg.ID(), data.TodoID); err != nil {
+		return fmt.Errorf("failed to complete todo: %w", err)
+	}
+	return nil
+}
 
-	switch event.Event {
-	case "todo_completed":
-		if event.TodoID == nil {
-			return fmt.Errorf("todo ID is required for todo_completed event")
-		}
-		err := w.todoUsecase.CompleteTodo(ctx, *event.TodoID)
-		if err != nil {
-			return fmt.Errorf("failed to complete todo: %w", err)
-		}
-		return nil
-	default:
-		return fmt.Errorf("unknown event type: %s", event.Event)
+func (w *TodoWorker) TodoSnoozeEnded(ctx context.Context, msg *events.Message, data dto.TodoSnoozeEndedData) error {
+	if data.TodoID == 0 {
+		return domain.Permanent(fmt.Errorf("todo ID is required for %s event", msg.Event.Type))
+	}
+	if err := w.todoUsecase.EndSnooze(ctx, msg.ID(), data.TodoID, data.Until); err != nil {
+		return fmt.Errorf("failed to end snooze: %w", err)
 	}
+	return nil
 }
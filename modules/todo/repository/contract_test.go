@@ -0,0 +1,524 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/database"
+	"github.com/nayeem-bd/Todo-App/internal/migrations"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// TestPostgresEnv names a DSN to run the Postgres contract against instead of
+// starting a throwaway server with initdb and pg_ctl from PATH.
+const TestPostgresEnv = "TEST_POSTGRES_DSN"
+
+var testTimeouts = database.Timeouts{Read: 5 * time.Second, Write: 5 * time.Second}
+
+func TestMemoryTodoRepository(t *testing.T) {
+	testTodoRepository(t, func(t *testing.T) domain.TodoRepository {
+		return NewMemoryTodoRepository()
+	})
+}
+
+func TestTodoRepository_SQLite(t *testing.T) {
+	testTodoRepository(t, func(t *testing.T) domain.TodoRepository {
+		db, err := config.ConnectDatabase(config.DatabaseConfig{
+			Driver:            config.DatabaseDriverSQLite,
+			Path:              filepath.Join(t.TempDir(), "todoapp.db"),
+			MaxOpenConnection: 4,
+			WriteTimeout:      5,
+		})
+		if err != nil {
+			t.Fatalf("ConnectDatabase() error = %v", err)
+		}
+		migrate(t, db)
+		return NewTodoRepository(database.NewCluster(db), testTimeouts)
+	})
+}
+
+func TestTodoRepository_SQLiteLocking(t *testing.T) {
+	db, err := config.ConnectDatabase(config.DatabaseConfig{
+		Driver:            config.DatabaseDriverSQLite,
+		Path:              filepath.Join(t.TempDir(), "todoapp.db"),
+		MaxOpenConnection: 4,
+		WriteTimeout:      5,
+	})
+	if err != nil {
+		t.Fatalf("ConnectDatabase() error = %v", err)
+	}
+	migrate(t, db)
+	testRowLocking(t, db)
+}
+
+func TestTodoRepository_Postgres(t *testing.T) {
+	db := postgresTestDB(t)
+	migrate(t, db)
+	testTodoRepository(t, func(t *testing.T) domain.TodoRepository {
+		if err := db.Exec("TRUNCATE todos RESTART IDENTITY").Error; err != nil {
+			t.Fatalf("failed to reset todos: %v", err)
+		}
+		return NewTodoRepository(database.NewCluster(db), testTimeouts)
+	})
+	t.Run("locking", func(t *testing.T) {
+		if err := db.Exec("TRUNCATE todos RESTART IDENTITY").Error; err != nil {
+			t.Fatalf("failed to reset todos: %v", err)
+		}
+		testRowLocking(t, db)
+	})
+}
+
+// testRowLocking runs two transactions that read the same todo with
+// GetByIDForUpdate and write it back incremented. The second must wait for
+// the first to commit, so neither update is lost. Each transaction is set up
+// like store.DataStore.WithTx, which this package cannot import.
+func testRowLocking(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	repo := NewTodoRepository(database.NewCluster(db), testTimeouts)
+	created, err := repo.Create(ctx, &domain.Todo{Title: "0", Description: "counter"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	var inside, overlaps atomic.Int32
+	increment := func(tx *gorm.DB) error {
+		txRepo := NewTodoRepository(database.NewCluster(tx), testTimeouts)
+		todo, err := txRepo.GetByIDForUpdate(ctx, created.ID)
+		if err != nil {
+			return err
+		}
+		if inside.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		// Give the other transaction time to read the row if it could
+		time.Sleep(50 * time.Millisecond)
+		count, err := strconv.Atoi(todo.Title)
+		if err != nil {
+			return err
+		}
+		todo.Title = strconv.Itoa(count + 1)
+		inside.Add(-1)
+		_, err = txRepo.Update(ctx, todo)
+		return err
+	}
+
+	start := make(chan struct{})
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			<-start
+			errs <- db.WithContext(ctx).Transaction(increment)
+		}()
+	}
+	close(start)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("transaction error = %v", err)
+		}
+	}
+
+	if n := overlaps.Load(); n != 0 {
+		t.Errorf("both transactions held the row at once %d time(s)", n)
+	}
+	got, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Title != "2" {
+		t.Errorf("counter = %s after two locked increments, want 2", got.Title)
+	}
+}
+
+// testTodoRepository is the contract every domain.TodoRepository must pass.
+// newRepo returns an empty repository.
+func testTodoRepository(t *testing.T, newRepo func(t *testing.T) domain.TodoRepository) {
+	ctx := context.Background()
+
+	t.Run("create assigns IDs, defaults and timestamps", func(t *testing.T) {
+		repo := newRepo(t)
+		before := time.Now().Add(-time.Second)
+
+		created, err := repo.Create(ctx, &domain.Todo{Title: "title", Description: "description"})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if created.ID == 0 {
+			t.Error("Create() did not assign an ID")
+		}
+		if created.Category != "default" {
+			t.Errorf("Create() category = %q, want the default", created.Category)
+		}
+		if created.CreatedAt.Before(before) || created.UpdatedAt.Before(before) {
+			t.Errorf("Create() timestamps = %v/%v, want them set", created.CreatedAt, created.UpdatedAt)
+		}
+
+		got, err := repo.GetByID(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.Title != "title" || got.Description != "description" || got.DoneAt != nil || got.SnoozedUntil != nil {
+			t.Errorf("GetByID() = %+v, want the created todo", got)
+		}
+		if !sameTime(got.CreatedAt, created.CreatedAt) {
+			t.Errorf("GetByID() created_at = %v, want %v", got.CreatedAt, created.CreatedAt)
+		}
+	})
+
+	t.Run("get all lists every todo by ID", func(t *testing.T) {
+		repo := newRepo(t)
+		todos, err := repo.GetAll(ctx)
+		if err != nil || len(todos) != 0 {
+			t.Fatalf("GetAll() on an empty repository = %d todos, %v", len(todos), err)
+		}
+
+		var ids []int
+		for i := 0; i < 5; i++ {
+			created, err := repo.Create(ctx, &domain.Todo{Title: fmt.Sprintf("todo %d", i), Description: "d"})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			ids = append(ids, created.ID)
+		}
+
+		todos, err = repo.GetAll(ctx)
+		if err != nil {
+			t.Fatalf("GetAll() error = %v", err)
+		}
+		if len(todos) != len(ids) {
+			t.Fatalf("GetAll() = %d todos, want %d", len(todos), len(ids))
+		}
+		for i, todo := range todos {
+			if todo.ID != ids[i] {
+				t.Errorf("GetAll()[%d] has ID %d, want %d", i, todo.ID, ids[i])
+			}
+		}
+	})
+
+	t.Run("list pages through todos by ID", func(t *testing.T) {
+		repo := newRepo(t)
+		var ids []int
+		for i := 0; i < 5; i++ {
+			created, err := repo.Create(ctx, &domain.Todo{Title: fmt.Sprintf("todo %d", i), Description: "d"})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			ids = append(ids, created.ID)
+		}
+
+		var pages [][]int
+		afterID := 0
+		for {
+			page, err := repo.List(ctx, afterID, 2)
+			if err != nil {
+				t.Fatalf("List(%d, 2) error = %v", afterID, err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			var pageIDs []int
+			for _, todo := range page {
+				pageIDs = append(pageIDs, todo.ID)
+			}
+			pages = append(pages, pageIDs)
+			afterID = page[len(page)-1].ID
+		}
+
+		want := [][]int{ids[0:2], ids[2:4], ids[4:5]}
+		if fmt.Sprint(pages) != fmt.Sprint(want) {
+			t.Errorf("List() pages = %v, want %v", pages, want)
+		}
+
+		// A todo created between pages shows up on a later one
+		created, err := repo.Create(ctx, &domain.Todo{Title: "late", Description: "d"})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		page, err := repo.List(ctx, ids[4], 2)
+		if err != nil || len(page) != 1 || page[0].ID != created.ID {
+			t.Errorf("List() after the last page = %v, %v, want the new todo", page, err)
+		}
+	})
+
+	t.Run("get all and list leave out snoozed todos", func(t *testing.T) {
+		repo := newRepo(t)
+		var ids []int
+		for i := 0; i < 3; i++ {
+			created, err := repo.Create(ctx, &domain.Todo{Title: fmt.Sprintf("todo %d", i), Description: "d"})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			ids = append(ids, created.ID)
+		}
+
+		// Snooze stores UTC truncated to seconds
+		snooze := func(id int, until time.Time) {
+			todo, err := repo.GetByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			until = until.UTC().Truncate(time.Second)
+			todo.SnoozedUntil = &until
+			if _, err := repo.Update(ctx, todo); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+		}
+		snooze(ids[1], time.Now().Add(time.Hour))
+		snooze(ids[2], time.Now().Add(-time.Hour))
+
+		want := fmt.Sprint([]int{ids[0], ids[2]})
+		todos, err := repo.GetAll(ctx)
+		if err != nil {
+			t.Fatalf("GetAll() error = %v", err)
+		}
+		if got := fmt.Sprint(todoIDs(todos)); got != want {
+			t.Errorf("GetAll() = %s, want %s", got, want)
+		}
+		page, err := repo.List(ctx, 0, 10)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if got := fmt.Sprint(todoIDs(page)); got != want {
+			t.Errorf("List() = %s, want %s", got, want)
+		}
+		if _, err := repo.GetByID(ctx, ids[1]); err != nil {
+			t.Errorf("GetByID() of a snoozed todo error = %v", err)
+		}
+	})
+
+	t.Run("missing todos are not found", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.GetByID(ctx, 404); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("GetByID() error = %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetByIDForUpdate(ctx, 404); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("GetByIDForUpdate() error = %v, want ErrNotFound", err)
+		}
+		if _, err := repo.Update(ctx, &domain.Todo{ID: 404, Title: "t", Description: "d"}); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Update() error = %v, want ErrNotFound", err)
+		}
+		if todos, _ := repo.GetAll(ctx); len(todos) != 0 {
+			t.Errorf("Update() of a missing todo created %d todos", len(todos))
+		}
+	})
+
+	t.Run("update keeps created_at and advances updated_at", func(t *testing.T) {
+		repo := newRepo(t)
+		created, err := repo.Create(ctx, &domain.Todo{Title: "title", Description: "description"})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		todo, err := repo.GetByIDForUpdate(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("GetByIDForUpdate() error = %v", err)
+		}
+		doneAt := time.Now().UTC().Truncate(time.Millisecond)
+		todo.Title = "updated"
+		todo.DoneAt = &doneAt
+		todo.CreatedAt = time.Time{}
+		if _, err := repo.Update(ctx, todo); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+
+		got, err := repo.GetByID(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.Title != "updated" || got.Description != "description" {
+			t.Errorf("GetByID() = %+v, want the update applied", got)
+		}
+		if got.DoneAt == nil || !sameTime(*got.DoneAt, doneAt) {
+			t.Errorf("GetByID() done_at = %v, want %v", got.DoneAt, doneAt)
+		}
+		if !sameTime(got.CreatedAt, created.CreatedAt) {
+			t.Errorf("GetByID() created_at = %v, want it kept at %v", got.CreatedAt, created.CreatedAt)
+		}
+		if !got.UpdatedAt.After(created.UpdatedAt) {
+			t.Errorf("GetByID() updated_at = %v, want it after %v", got.UpdatedAt, created.UpdatedAt)
+		}
+	})
+
+	t.Run("concurrent updates all land", func(t *testing.T) {
+		repo := newRepo(t)
+		const writers = 8
+
+		var ids []int
+		for i := 0; i < writers; i++ {
+			created, err := repo.Create(ctx, &domain.Todo{Title: "title", Description: "d"})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			ids = append(ids, created.ID)
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, writers*writers)
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for _, id := range ids {
+					todo, err := repo.GetByID(ctx, id)
+					if err != nil {
+						errs <- err
+						return
+					}
+					todo.Title = fmt.Sprintf("writer %d", w)
+					if _, err := repo.Update(ctx, todo); err != nil {
+						errs <- err
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("concurrent Update() error = %v", err)
+		}
+
+		todos, err := repo.GetAll(ctx)
+		if err != nil || len(todos) != writers {
+			t.Fatalf("GetAll() after concurrent updates = %d todos, %v", len(todos), err)
+		}
+		for _, todo := range todos {
+			if todo.Title == "title" {
+				t.Errorf("todo %d was not updated", todo.ID)
+			}
+		}
+	})
+}
+
+// sameTime compares timestamps at the microsecond precision Postgres keeps.
+func todoIDs(todos []*domain.Todo) []int {
+	ids := make([]int, 0, len(todos))
+	for _, todo := range todos {
+		ids = append(ids, todo.ID)
+	}
+	return ids
+}
+
+func sameTime(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -time.Microsecond && d < time.Microsecond
+}
+
+// migrate applies the embedded migrations and closes db after the test.
+func migrate(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	embedded, err := migrations.Embedded(db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("Embedded() error = %v", err)
+	}
+	if _, err := migrations.New(db, embedded).Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+}
+
+// postgresTestDB connects to a schema created for this run and dropped
+// after it, so TEST_POSTGRES_DSN may point at a database that holds other
+// data: the contract only ever truncates its own tables.
+func postgresTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := postgresDSN(t)
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to Postgres: %v", err)
+	}
+	adminDB, err := admin.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := pgx.Identifier{fmt.Sprintf("todo_contract_%d", time.Now().UnixNano())}
+	if err := admin.Exec("CREATE SCHEMA " + schema.Sanitize()).Error; err != nil {
+		_ = adminDB.Close()
+		t.Fatalf("failed to create the test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + schema.Sanitize() + " CASCADE").Error; err != nil {
+			t.Errorf("failed to drop the test schema: %v", err)
+		}
+		_ = adminDB.Close()
+	})
+
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", TestPostgresEnv, err)
+	}
+	connConfig.RuntimeParams["search_path"] = schema[0]
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: stdlib.OpenDB(*connConfig)}), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatalf("failed to connect to Postgres: %v", err)
+	}
+	return db
+}
+
+// postgresDSN returns TEST_POSTGRES_DSN, or starts a server in a temporary
+// directory for the duration of the test. Without either the test is
+// skipped.
+func postgresDSN(t *testing.T) string {
+	t.Helper()
+	if dsn := os.Getenv(TestPostgresEnv); dsn != "" {
+		return dsn
+	}
+
+	initdb, errInit := exec.LookPath("initdb")
+	pgCtl, errCtl := exec.LookPath("pg_ctl")
+	if errInit != nil || errCtl != nil {
+		t.Skipf("set %s or put initdb and pg_ctl on PATH to run the Postgres contract", TestPostgresEnv)
+	}
+	if os.Geteuid() == 0 {
+		t.Skipf("Postgres refuses to run as root; set %s instead", TestPostgresEnv)
+	}
+
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data")
+	port := freePort(t)
+	if out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "--auth=trust").CombinedOutput(); err != nil {
+		t.Fatalf("initdb failed: %v\n%s", err, out)
+	}
+	start := exec.Command(pgCtl, "-D", data, "-l", filepath.Join(dir, "postgres.log"), "-w",
+		"-o", fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1", port, dir), "start")
+	if out, err := start.CombinedOutput(); err != nil {
+		t.Fatalf("pg_ctl start failed: %v\n%s", err, out)
+	}
+	t.Cleanup(func() {
+		_ = exec.Command(pgCtl, "-D", data, "-m", "immediate", "stop").Run()
+	})
+
+	return fmt.Sprintf("host=127.0.0.1 port=%d user=postgres dbname=postgres sslmode=disable", port)
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nayeem-bd/Todo-App/domain"
+)
+
+// MemoryTodoRepository keeps todos in process for tests and demos that need
+// no database. It honours the TodoRepository contract except for locking:
+// GetByIDForUpdate locks nothing. Callers get copies, so changing a returned
+// todo has no effect until it is passed to Update.
+type MemoryTodoRepository struct {
+	mu     sync.Mutex
+	todos  map[int]domain.Todo
+	lastID int
+}
+
+func NewMemoryTodoRepository() *MemoryTodoRepository {
+	return &MemoryTodoRepository{todos: make(map[int]domain.Todo)}
+}
+
+func (r *MemoryTodoRepository) GetAll(ctx context.Context) ([]*domain.Todo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	todos := make([]*domain.Todo, 0, len(r.todos))
+	for _, todo := range r.todos {
+		if todo.SnoozedUntil != nil && todo.SnoozedUntil.After(now) {
+			continue
+		}
+		todo := todo
+		todos = append(todos, &todo)
+	}
+	sort.Slice(todos, func(i, j int) bool { return todos[i].ID < todos[j].ID })
+	return todos, nil
+}
+
+func (r *MemoryTodoRepository) List(ctx context.Context, afterID, limit int) ([]*domain.Todo, error) {
+	todos, err := r.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page := make([]*domain.Todo, 0, max(limit, 0))
+	for _, todo := range todos {
+		if len(page) == limit {
+			break
+		}
+		if todo.ID > afterID {
+			page = append(page, todo)
+		}
+	}
+	return page, nil
+}
+
+func (r *MemoryTodoRepository) Create(ctx context.Context, todo *domain.Todo) (*domain.Todo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if todo.ID == 0 {
+		r.lastID++
+		todo.ID = r.lastID
+	} else if _, ok := r.todos[todo.ID]; ok {
+		return nil, domain.ErrConflict
+	} else if todo.ID > r.lastID {
+		r.lastID = todo.ID
+	}
+
+	now := time.Now()
+	if todo.CreatedAt.IsZero() {
+		todo.CreatedAt = now
+	}
+	if todo.UpdatedAt.IsZero() {
+		todo.UpdatedAt = now
+	}
+	if todo.Category == "" {
+		todo.Category = "default"
+	}
+	r.todos[todo.ID] = *todo
+	return todo, nil
+}
+
+func (r *MemoryTodoRepository) GetByID(ctx context.Context, id int) (*domain.Todo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, ok := r.todos[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &todo, nil
+}
+
+func (r *MemoryTodoRepository) GetByIDForUpdate(ctx context.Context, id int) (*domain.Todo, error) {
+	return r.GetByID(ctx, id)
+}
+
+// Update keeps the stored CreatedAt, like the database repositories.
+func (r *MemoryTodoRepository) Update(ctx context.Context, todo *domain.Todo) (*domain.Todo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.todos[todo.ID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	todo.UpdatedAt = time.Now()
+	stored := *todo
+	stored.CreatedAt = existing.CreatedAt
+	r.todos[todo.ID] = stored
+	return todo, nil
+}
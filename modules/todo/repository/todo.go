@@ -2,48 +2,109 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"time"
+
 	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/internal/database"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// TodoRepository serves GetAll, List and GetByID from the cluster's read replicas
+// and everything else, including locking reads, from the primary.
 type TodoRepository struct {
-	db *gorm.DB
+	cluster  *database.Cluster
+	db       *gorm.DB
+	timeouts database.Timeouts
 }
 
-func NewTodoRepository(db *gorm.DB) *TodoRepository {
-	return &TodoRepository{db: db}
+func NewTodoRepository(cluster *database.Cluster, timeouts database.Timeouts) *TodoRepository {
+	return &TodoRepository{cluster: cluster, db: cluster.Primary(), timeouts: timeouts}
 }
 
 func (r *TodoRepository) GetAll(ctx context.Context) ([]*domain.Todo, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Read)
+	defer cancel()
+
+	var todos []*domain.Todo
+	err := r.cluster.Read(ctx, func(db *gorm.DB) error {
+		return db.WithContext(ctx).Scopes(notSnoozed).Order("id").Find(&todos).Error
+	})
+	if err != nil {
+		return nil, database.Error(ctx, err)
+	}
+	return todos, nil
+}
+
+func (r *TodoRepository) List(ctx context.Context, afterID, limit int) ([]*domain.Todo, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Read)
+	defer cancel()
+
 	var todos []*domain.Todo
-	if err := r.db.Find(&todos).Error; err != nil {
-		return nil, err
+	err := r.cluster.Read(ctx, func(db *gorm.DB) error {
+		return db.WithContext(ctx).Scopes(notSnoozed).Where("id > ?", afterID).Order("id").Limit(limit).Find(&todos).Error
+	})
+	if err != nil {
+		return nil, database.Error(ctx, err)
 	}
 	return todos, nil
 }
 
+// notSnoozed leaves out todos snoozed until a later time. Snooze stores
+// UTC, so the comparison uses UTC as well.
+func notSnoozed(db *gorm.DB) *gorm.DB {
+	return db.Where("snoozed_until IS NULL OR snoozed_until <= ?", time.Now().UTC())
+}
+
 func (r *TodoRepository) Create(ctx context.Context, todo *domain.Todo) (*domain.Todo, error) {
-	if err := r.db.Create(todo).Error; err != nil {
-		return nil, err
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Write)
+	defer cancel()
+
+	database.MarkWrite(ctx)
+	if err := r.db.WithContext(ctx).Create(todo).Error; err != nil {
+		return nil, database.Error(ctx, err)
 	}
 	return todo, nil
 }
 
 func (r *TodoRepository) GetByID(ctx context.Context, id int) (*domain.Todo, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Read)
+	defer cancel()
+
+	var todo domain.Todo
+	err := r.cluster.Read(ctx, func(db *gorm.DB) error {
+		return db.WithContext(ctx).First(&todo, id).Error
+	})
+	if err != nil {
+		return nil, database.Error(ctx, err)
+	}
+	return &todo, nil
+}
+
+func (r *TodoRepository) GetByIDForUpdate(ctx context.Context, id int) (*domain.Todo, error) {
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Read)
+	defer cancel()
+
 	var todo domain.Todo
-	if err := r.db.First(&todo, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, nil
-		}
-		return nil, err
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&todo, id).Error; err != nil {
+		return nil, database.Error(ctx, err)
 	}
 	return &todo, nil
 }
 
+// Update returns domain.ErrNotFound instead of inserting a todo that does not
+// exist.
 func (r *TodoRepository) Update(ctx context.Context, todo *domain.Todo) (*domain.Todo, error) {
-	if err := r.db.Save(todo).Error; err != nil {
-		return nil, err
+	ctx, cancel := database.WithTimeout(ctx, r.timeouts.Write)
+	defer cancel()
+
+	database.MarkWrite(ctx)
+	result := r.db.WithContext(ctx).Model(todo).Select("*").Omit("created_at").Updates(todo)
+	if result.Error != nil {
+		return nil, database.Error(ctx, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, domain.ErrNotFound
 	}
 	return todo, nil
 }
@@ -3,31 +3,75 @@ package usecase
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/domain/dto"
 	"github.com/nayeem-bd/Todo-App/internal/config"
 	"github.com/nayeem-bd/Todo-App/internal/logger"
 	"github.com/nayeem-bd/Todo-App/internal/store"
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/nayeem-bd/Todo-App/internal/utils"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	todosCacheKey = "todos"
+	// defaultTodosCacheTTL applies until SetCacheTTL is called
+	defaultTodosCacheTTL = 30 * time.Second
+
+	// Consumers scope processed event IDs per handler
+	completeTodoConsumer  = "todo.complete"
+	endSnoozeTodoConsumer = "todo.end_snooze"
+
+	completionPollInterval = 100 * time.Millisecond
+)
+
+// Completion decides whether Complete applies the change itself or leaves it
+// to the worker.
+type Completion struct {
+	// Strategy is one of the config.Completion* strategies; empty is async
+	Strategy string
+	// WaitTimeout bounds how long Complete waits for the worker
+	WaitTimeout time.Duration
+	// BrokerUp reports whether events currently reach the worker. The
+	// async_fallback strategy completes synchronously while it fails.
+	BrokerUp func(ctx context.Context) error
+}
+
 type TodoUsecase struct {
-	store  store.Store
-	cacher *config.Cache
-	queue  *config.Queue
+	store      store.Store
+	cacher     domain.Cache
+	completion Completion
+	cacheTTL   atomic.Int64
 }
 
-func NewTodoUsecase(store store.Store, cacher *config.Cache, queue *config.Queue) *TodoUsecase {
-	return &TodoUsecase{store: store, cacher: cacher, queue: queue}
+func NewTodoUsecase(store store.Store, cacher domain.Cache) *TodoUsecase {
+	todoUsecase := &TodoUsecase{store: store, cacher: cacher}
+	todoUsecase.cacheTTL.Store(int64(defaultTodosCacheTTL))
+	return todoUsecase
+}
+
+// SetCacheTTL changes how long the todo list is cached. It is safe to call
+// while requests are served.
+func (todoUsecase *TodoUsecase) SetCacheTTL(ttl time.Duration) {
+	todoUsecase.cacheTTL.Store(int64(ttl))
+}
+
+// SetCompletion replaces the default async completion.
+func (todoUsecase *TodoUsecase) SetCompletion(completion Completion) {
+	todoUsecase.completion = completion
 }
 
 func (todoUsecase *TodoUsecase) GetAll(ctx context.Context) ([]*domain.Todo, error) {
-	todoStr, err := todoUsecase.cacher.Get(ctx, "todos")
 	var todos []*domain.Todo
+	todoStr, err := todoUsecase.cacher.Get(ctx, todosCacheKey)
 	if err == nil {
 		if err := json.Unmarshal([]byte(todoStr), &todos); err == nil {
-			return todos, err
+			return todos, nil
 		}
+	} else {
+		logCacheError("read", err)
 	}
 
 	todos, err = todoUsecase.store.TodoRepository().GetAll(ctx)
@@ -35,16 +79,36 @@ func (todoUsecase *TodoUsecase) GetAll(ctx context.Context) ([]*domain.Todo, err
 		return nil, err
 	}
 
-	// Cache the todos
+	// Cache the todos; the database result is served even if this fails
 	todoBytes, err := json.Marshal(todos)
 	if err != nil {
-		return nil, err
+		logCacheError("encode", err)
+		return todos, nil
+	}
+	if err := todoUsecase.cacher.Set(ctx, todosCacheKey, string(todoBytes), time.Duration(todoUsecase.cacheTTL.Load())); err != nil {
+		logCacheError("write", err)
 	}
-	_ = todoUsecase.cacher.Set(ctx, "todos", string(todoBytes), 30*time.Second)
 
 	return todos, nil
 }
 
+// invalidateTodos drops the cached todo list after a change to which todos it
+// shows. A failure leaves the stale list for at most the cache TTL.
+func (todoUsecase *TodoUsecase) invalidateTodos(ctx context.Context) {
+	if err := todoUsecase.cacher.Delete(ctx, todosCacheKey); err != nil {
+		logCacheError("invalidate", err)
+	}
+}
+
+// logCacheError skips misses and the fail-fast error returned while the
+// cache is degraded, which is already reported once by the cache itself.
+func logCacheError(op string, err error) {
+	if errors.Is(err, domain.ErrCacheMiss) || errors.Is(err, domain.ErrCacheUnavailable) {
+		return
+	}
+	logger.Warn("Cache ", op, " failed: ", err)
+}
+
 func (todoUsecase *TodoUsecase) Create(ctx context.Context, todo *domain.Todo) (*domain.Todo, error) {
 	if todo.Category == "" {
 		todo.Category = "default"
@@ -61,55 +125,258 @@ func (todoUsecase *TodoUsecase) GetByID(ctx context.Context, id int) (*domain.To
 	return todoUsecase.store.TodoRepository().GetByID(ctx, id)
 }
 
-func (todoUsecase *TodoUsecase) Complete(ctx context.Context, id int) error {
-	todo, err := todoUsecase.GetByID(ctx, id)
-	if err != nil {
+// Complete records a todo completed event. When completing synchronously the
+// todo is also updated in the same transaction and the event is marked as
+// processed, so the worker skips it while other consumers still see it.
+func (todoUsecase *TodoUsecase) Complete(ctx context.Context, id int, wait bool) (*domain.Todo, error) {
+	sync := todoUsecase.completeSynchronously(ctx)
+
+	var todo *domain.Todo
+	err := todoUsecase.store.WithTx(ctx, func(tx store.Store) error {
+		var err error
+		todo, err = tx.TodoRepository().GetByIDForUpdate(ctx, id)
+		if err != nil || todo.DoneAt != nil {
+			return err
+		}
+
+		eventID, err := emitEvent(ctx, tx, dto.EventTodoCompleted, dto.TodoCompletedDataVersion,
+			todoSubject(todo.ID), dto.TodoCompletedData{TodoID: todo.ID})
+		if err != nil || !sync {
+			return err
+		}
+
+		if _, err := processOnce(ctx, tx, completeTodoConsumer, eventID); err != nil {
+			return err
+		}
+		now := time.Now()
+		todo.DoneAt = &now
+		todo, err = tx.TodoRepository().Update(ctx, todo)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if todo.DoneAt != nil || !wait {
+		return todo, nil
 	}
+	return todoUsecase.waitForCompletion(ctx, id)
+}
+
+func (todoUsecase *TodoUsecase) completeSynchronously(ctx context.Context) bool {
+	switch todoUsecase.completion.Strategy {
+	case config.CompletionSync:
+		return true
+	case config.CompletionAsyncFallback:
+		if todoUsecase.completion.BrokerUp == nil {
+			return false
+		}
+		if err := todoUsecase.completion.BrokerUp(ctx); err != nil {
+			logger.Warn("Broker unavailable, completing todo synchronously: ", err)
+			return true
+		}
+		return false
+	default:
+		return false
+	}
+}
 
-	ch, err := todoUsecase.queue.Conn.Channel()
+// waitForCompletion polls the todo until the worker has completed it.
+func (todoUsecase *TodoUsecase) waitForCompletion(ctx context.Context, id int) (*domain.Todo, error) {
+	ctx, cancel := context.WithTimeout(ctx, todoUsecase.completion.WaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(completionPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, domain.ErrCompletionPending
+		case <-ticker.C:
+		}
+
+		todo, err := todoUsecase.store.TodoRepository().GetByID(ctx, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, domain.ErrCompletionPending
+			}
+			return nil, err
+		}
+		if todo.DoneAt != nil {
+			return todo, nil
+		}
+	}
+}
+
+// emitEvent records an event envelope in the outbox of s and returns its ID.
+// Pass the transaction-scoped store when the event belongs to a change so
+// both commit together.
+func emitEvent(ctx context.Context, s store.Store, eventType, dataVersion, subject string, data interface{}) (string, error) {
+	envelope, body, err := encodeEvent(ctx, eventType, dataVersion, subject, data)
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer ch.Close()
 
-	message := map[string]interface{}{
-		"todo_id": todo.ID,
-		"event":   "todo_completed",
+	_, err = s.OutboxRepository().Add(ctx, &domain.OutboxEvent{
+		EventID:     envelope.ID,
+		EventType:   envelope.Type,
+		ContentType: dto.CloudEventsContentType,
+		Payload:     body,
+	})
+	if err != nil {
+		return "", err
 	}
+	return envelope.ID, nil
+}
 
-	messageBytes, err := json.Marshal(message)
+// scheduleEvent records an event to be published at dueAt, with the same
+// transactional guarantee as emitEvent.
+func scheduleEvent(ctx context.Context, s store.Store, dueAt time.Time, eventType, dataVersion, subject string, data interface{}) error {
+	envelope, body, err := encodeEvent(ctx, eventType, dataVersion, subject, data)
 	if err != nil {
 		return err
 	}
 
-	err = ch.Publish(
-		todoUsecase.queue.ExchangeName,
-		todoUsecase.queue.RoutingKey,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        messageBytes,
-		},
-	)
-
+	_, err = s.ScheduledMessageRepository().Add(ctx, &domain.ScheduledMessage{
+		MessageID:   envelope.ID,
+		EventType:   envelope.Type,
+		ContentType: dto.CloudEventsContentType,
+		Payload:     body,
+		DueAt:       dueAt,
+	})
 	return err
 }
 
-func (todoUsecase *TodoUsecase) CompleteTodo(ctx context.Context, id int) error {
-	todo, err := todoUsecase.GetByID(ctx, id)
+func encodeEvent(ctx context.Context, eventType, dataVersion, subject string, data interface{}) (*dto.Envelope, []byte, error) {
+	envelope, err := dto.NewEnvelope(utils.NewID(), eventType, dataVersion, subject, data)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	if todo.DoneAt != nil {
-		logger.Info("Todo already completed ", "todo_id: ", todo.ID)
-		return nil
+	envelope.CorrelationID = utils.CorrelationID(ctx)
+	envelope.Tenant = utils.Tenant(ctx)
+
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, nil, err
 	}
-	now := time.Now()
-	todo.DoneAt = &now
+	return envelope, body, nil
+}
 
-	_, err = todoUsecase.store.TodoRepository().Update(ctx, todo)
+// processOnce records eventID for consumer in the transaction of tx and
+// reports false when it was already processed. Events without an ID (legacy
+// publishers) are always processed.
+func processOnce(ctx context.Context, tx store.Store, consumer, eventID string) (bool, error) {
+	if eventID == "" {
+		return true, nil
+	}
+	first, err := tx.ProcessedMessageRepository().MarkProcessed(ctx, consumer, eventID)
+	if err != nil {
+		return false, fmt.Errorf("failed to record processed event: %w", err)
+	}
+	if !first {
+		logger.Info("Skipping duplicate event ", "event_id: ", eventID)
+	}
+	return first, nil
+}
 
-	return err
+func todoSubject(id int) string {
+	return fmt.Sprintf("todos/%d", id)
+}
+
+// CompleteTodo handles a todo completed event. The event ID is recorded in
+// the same transaction as the update, so a redelivered event is skipped.
+func (todoUsecase *TodoUsecase) CompleteTodo(ctx context.Context, eventID string, id int) error {
+	return todoUsecase.store.WithTx(ctx, func(tx store.Store) error {
+		if first, err := processOnce(ctx, tx, completeTodoConsumer, eventID); err != nil || !first {
+			return err
+		}
+
+		todo, err := tx.TodoRepository().GetByIDForUpdate(ctx, id)
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.Permanent(fmt.Errorf("todo %d: %w", id, err))
+		}
+		if err != nil {
+			return err
+		}
+		if todo.DoneAt != nil {
+			logger.Info("Todo already completed ", "todo_id: ", todo.ID)
+			return nil
+		}
+		now := time.Now()
+		todo.DoneAt = &now
+
+		_, err = tx.TodoRepository().Update(ctx, todo)
+		return err
+	})
+}
+
+// Snooze hides the todo until the given time and schedules the event that
+// ends the snooze, both in one transaction. Snoozing again replaces the
+// previous time; the earlier event is then ignored by EndSnooze.
+func (todoUsecase *TodoUsecase) Snooze(ctx context.Context, id int, until time.Time) (*domain.Todo, error) {
+	var snoozed *domain.Todo
+	err := todoUsecase.store.WithTx(ctx, func(tx store.Store) error {
+		todo, err := tx.TodoRepository().GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if todo.DoneAt != nil {
+			return domain.ErrTodoCompleted
+		}
+
+		// Stored timestamps have less precision than time.Time, and
+		// EndSnooze compares them
+		until = until.UTC().Truncate(time.Second)
+		todo.SnoozedUntil = &until
+		if snoozed, err = tx.TodoRepository().Update(ctx, todo); err != nil {
+			return err
+		}
+
+		return scheduleEvent(ctx, tx, until, dto.EventTodoSnoozeEnded, dto.TodoSnoozeEndedDataVersion,
+			todoSubject(todo.ID), dto.TodoSnoozeEndedData{TodoID: todo.ID, Until: until})
+	})
+	if err != nil {
+		return nil, err
+	}
+	todoUsecase.invalidateTodos(ctx)
+	return snoozed, nil
+}
+
+// EndSnooze handles a snooze ended event. It only clears the snooze the
+// event was scheduled for, so an older event does not cut a later snooze
+// short.
+func (todoUsecase *TodoUsecase) EndSnooze(ctx context.Context, eventID string, id int, until time.Time) error {
+	cleared := false
+	err := todoUsecase.store.WithTx(ctx, func(tx store.Store) error {
+		if first, err := processOnce(ctx, tx, endSnoozeTodoConsumer, eventID); err != nil || !first {
+			return err
+		}
+
+		todo, err := tx.TodoRepository().GetByIDForUpdate(ctx, id)
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.Permanent(fmt.Errorf("todo %d: %w", id, err))
+		}
+		if err != nil {
+			return err
+		}
+		if todo.SnoozedUntil == nil || !todo.SnoozedUntil.Equal(until) {
+			logger.Info("Ignoring stale snooze ", "todo_id: ", todo.ID)
+			return nil
+		}
+
+		todo.SnoozedUntil = nil
+		if _, err = tx.TodoRepository().Update(ctx, todo); err != nil {
+			return err
+		}
+		cleared = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if cleared {
+		todoUsecase.invalidateTodos(ctx)
+	}
+	return nil
 }
@@ -7,6 +7,10 @@ import (
 	"time"
 
 	"github.com/nayeem-bd/Todo-App/domain"
+	"github.com/nayeem-bd/Todo-App/domain/dto"
+	"github.com/nayeem-bd/Todo-App/internal/config"
+	"github.com/nayeem-bd/Todo-App/internal/store"
+	"github.com/nayeem-bd/Todo-App/internal/utils"
 )
 
 // MockTodoRepository is a mock implementation of TodoRepository for testing
@@ -24,6 +28,19 @@ func (m *MockTodoRepository) GetAll(ctx context.Context) ([]*domain.Todo, error)
 	return m.todos, nil
 }
 
+func (m *MockTodoRepository) List(ctx context.Context, afterID, limit int) ([]*domain.Todo, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var page []*domain.Todo
+	for _, todo := range m.todos {
+		if todo.ID > afterID && len(page) < limit {
+			page = append(page, todo)
+		}
+	}
+	return page, nil
+}
+
 func (m *MockTodoRepository) Create(ctx context.Context, todo *domain.Todo) (*domain.Todo, error) {
 	if m.createFunc != nil {
 		return m.createFunc(ctx, todo)
@@ -52,18 +69,184 @@ func (m *MockTodoRepository) GetByID(ctx context.Context, id int) (*domain.Todo,
 			return todo, nil
 		}
 	}
-	return nil, errors.New("todo not found")
+	return nil, domain.ErrNotFound
+}
+
+func (m *MockTodoRepository) GetByIDForUpdate(ctx context.Context, id int) (*domain.Todo, error) {
+	return m.GetByID(ctx, id)
+}
+
+func (m *MockTodoRepository) Update(ctx context.Context, todo *domain.Todo) (*domain.Todo, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for i, existing := range m.todos {
+		if existing.ID == todo.ID {
+			m.todos[i] = todo
+			return todo, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+// MockCache is a mock implementation of domain.Cache for testing
+type MockCache struct {
+	values map[string]string
+	err    error
+}
+
+func (m *MockCache) Get(ctx context.Context, key string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	value, ok := m.values[key]
+	if !ok {
+		return "", domain.ErrCacheMiss
+	}
+	return value, nil
+}
+
+func (m *MockCache) Set(ctx context.Context, key string, value string, expiration time.Duration) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.values == nil {
+		m.values = make(map[string]string)
+	}
+	m.values[key] = value
+	return nil
+}
+
+func (m *MockCache) Delete(ctx context.Context, key string) error {
+	if m.err != nil {
+		return m.err
+	}
+	delete(m.values, key)
+	return nil
+}
+
+// MockOutboxRepository is a mock implementation of OutboxRepository for testing
+type MockOutboxRepository struct {
+	events []*domain.OutboxEvent
+	err    error
+}
+
+func (m *MockOutboxRepository) Add(ctx context.Context, event *domain.OutboxEvent) (*domain.OutboxEvent, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	event.ID = int64(len(m.events) + 1)
+	m.events = append(m.events, event)
+	return event, nil
+}
+
+func (m *MockOutboxRepository) FetchPending(ctx context.Context, limit int) ([]*domain.OutboxEvent, error) {
+	return m.events, m.err
+}
+
+func (m *MockOutboxRepository) MarkSent(ctx context.Context, id int64) error {
+	return m.err
+}
+
+func (m *MockOutboxRepository) MarkFailed(ctx context.Context, id int64, lastError string, availableAt time.Time) error {
+	return m.err
+}
+
+func (m *MockOutboxRepository) MarkParked(ctx context.Context, id int64, lastError string) error {
+	return m.err
+}
+
+// MockProcessedMessageRepository is a mock implementation of
+// ProcessedMessageRepository for testing
+type MockProcessedMessageRepository struct {
+	processed map[string]bool
+	err       error
+}
+
+func (m *MockProcessedMessageRepository) MarkProcessed(ctx context.Context, consumer, messageID string) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	if m.processed == nil {
+		m.processed = make(map[string]bool)
+	}
+	key := consumer + "/" + messageID
+	if m.processed[key] {
+		return false, nil
+	}
+	m.processed[key] = true
+	return true, nil
+}
+
+func (m *MockProcessedMessageRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	return 0, m.err
+}
+
+// MockScheduledMessageRepository is a mock implementation of
+// ScheduledMessageRepository for testing
+type MockScheduledMessageRepository struct {
+	msgs []*domain.ScheduledMessage
+	err  error
+}
+
+func (m *MockScheduledMessageRepository) Add(ctx context.Context, msg *domain.ScheduledMessage) (*domain.ScheduledMessage, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	msg.ID = int64(len(m.msgs) + 1)
+	m.msgs = append(m.msgs, msg)
+	return msg, nil
+}
+
+func (m *MockScheduledMessageRepository) FetchDue(ctx context.Context, before time.Time, limit int) ([]*domain.ScheduledMessage, error) {
+	return m.msgs, m.err
+}
+
+func (m *MockScheduledMessageRepository) MarkSent(ctx context.Context, id int64) error {
+	return m.err
+}
+
+func (m *MockScheduledMessageRepository) MarkFailed(ctx context.Context, id int64, lastError string, availableAt time.Time) error {
+	return m.err
 }
 
 // MockStore is a mock implementation of Store for testing
 type MockStore struct {
-	todoRepo domain.TodoRepository
+	todoRepo      domain.TodoRepository
+	outboxRepo    *MockOutboxRepository
+	processedRepo *MockProcessedMessageRepository
+	scheduledRepo *MockScheduledMessageRepository
 }
 
 func (m *MockStore) TodoRepository() domain.TodoRepository {
 	return m.todoRepo
 }
 
+func (m *MockStore) OutboxRepository() domain.OutboxRepository {
+	if m.outboxRepo == nil {
+		m.outboxRepo = &MockOutboxRepository{}
+	}
+	return m.outboxRepo
+}
+
+func (m *MockStore) ProcessedMessageRepository() domain.ProcessedMessageRepository {
+	if m.processedRepo == nil {
+		m.processedRepo = &MockProcessedMessageRepository{}
+	}
+	return m.processedRepo
+}
+
+func (m *MockStore) ScheduledMessageRepository() domain.ScheduledMessageRepository {
+	if m.scheduledRepo == nil {
+		m.scheduledRepo = &MockScheduledMessageRepository{}
+	}
+	return m.scheduledRepo
+}
+
+func (m *MockStore) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	return fn(m)
+}
+
 func TestTodoUsecase_GetAll(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -105,7 +288,7 @@ func TestTodoUsecase_GetAll(t *testing.T) {
 				err:   tt.err,
 			}
 			mockStore := &MockStore{todoRepo: mockRepo}
-			usecase := NewTodoUsecase(mockStore)
+			usecase := NewTodoUsecase(mockStore, &MockCache{})
 
 			ctx := context.Background()
 			result, err := usecase.GetAll(ctx)
@@ -179,7 +362,7 @@ func TestTodoUsecase_Create(t *testing.T) {
 				err: tt.err,
 			}
 			mockStore := &MockStore{todoRepo: mockRepo}
-			usecase := NewTodoUsecase(mockStore)
+			usecase := NewTodoUsecase(mockStore, &MockCache{})
 
 			ctx := context.Background()
 			result, err := usecase.Create(ctx, tt.input)
@@ -242,7 +425,7 @@ func TestTodoUsecase_GetByID(t *testing.T) {
 			name: "todo not found",
 			id:   999,
 			mockFunc: func(ctx context.Context, id int) (*domain.Todo, error) {
-				return nil, errors.New("todo not found")
+				return nil, domain.ErrNotFound
 			},
 			wantErr:  true,
 			wantTodo: nil,
@@ -264,7 +447,7 @@ func TestTodoUsecase_GetByID(t *testing.T) {
 				getByIDFunc: tt.mockFunc,
 			}
 			mockStore := &MockStore{todoRepo: mockRepo}
-			usecase := NewTodoUsecase(mockStore)
+			usecase := NewTodoUsecase(mockStore, &MockCache{})
 
 			ctx := context.Background()
 			result, err := usecase.GetByID(ctx, tt.id)
@@ -291,3 +474,412 @@ func TestTodoUsecase_GetByID(t *testing.T) {
 		})
 	}
 }
+
+func TestTodoUsecase_GetAll_CacheUnavailable(t *testing.T) {
+	todos := []*domain.Todo{{ID: 1, Title: "Test Todo 1"}}
+
+	tests := []struct {
+		name     string
+		cacheErr error
+	}{
+		{name: "cache degraded", cacheErr: domain.ErrCacheUnavailable},
+		{name: "cache error", cacheErr: errors.New("connection refused")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := &MockStore{todoRepo: &MockTodoRepository{todos: todos}}
+			usecase := NewTodoUsecase(mockStore, &MockCache{err: tt.cacheErr})
+
+			result, err := usecase.GetAll(context.Background())
+			if err != nil {
+				t.Fatalf("TodoUsecase.GetAll() error = %v, want nil", err)
+			}
+			if len(result) != 1 {
+				t.Errorf("TodoUsecase.GetAll() returned %d todos, want 1", len(result))
+			}
+		})
+	}
+}
+
+func TestTodoUsecase_GetAll_CacheHit(t *testing.T) {
+	cache := &MockCache{}
+	mockRepo := &MockTodoRepository{todos: []*domain.Todo{{ID: 1, Title: "Test Todo 1"}}}
+	usecase := NewTodoUsecase(&MockStore{todoRepo: mockRepo}, cache)
+
+	if _, err := usecase.GetAll(context.Background()); err != nil {
+		t.Fatalf("TodoUsecase.GetAll() error = %v", err)
+	}
+
+	mockRepo.err = errors.New("database error")
+	result, err := usecase.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("TodoUsecase.GetAll() should be served from cache, got error %v", err)
+	}
+	if len(result) != 1 {
+		t.Errorf("TodoUsecase.GetAll() returned %d todos, want 1", len(result))
+	}
+}
+
+func TestTodoUsecase_Complete(t *testing.T) {
+	tests := []struct {
+		name      string
+		todos     []*domain.Todo
+		id        int
+		outboxErr error
+		wantErr   bool
+		wantEvent bool
+	}{
+		{
+			name:      "records todo_completed event in the outbox",
+			todos:     []*domain.Todo{{ID: 1, Title: "Test Todo"}},
+			id:        1,
+			wantEvent: true,
+		},
+		{
+			name:    "todo not found",
+			todos:   []*domain.Todo{{ID: 1, Title: "Test Todo"}},
+			id:      2,
+			wantErr: true,
+		},
+		{
+			name:      "outbox write fails",
+			todos:     []*domain.Todo{{ID: 1, Title: "Test Todo"}},
+			id:        1,
+			outboxErr: errors.New("database error"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outboxRepo := &MockOutboxRepository{err: tt.outboxErr}
+			mockStore := &MockStore{todoRepo: &MockTodoRepository{todos: tt.todos}, outboxRepo: outboxRepo}
+			usecase := NewTodoUsecase(mockStore, &MockCache{})
+
+			ctx := utils.WithCorrelationID(context.Background(), "corr-1")
+			_, err := usecase.Complete(ctx, tt.id, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TodoUsecase.Complete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantEvent {
+				return
+			}
+			if len(outboxRepo.events) != 1 {
+				t.Fatalf("TodoUsecase.Complete() recorded %d events, want 1", len(outboxRepo.events))
+			}
+			recorded := outboxRepo.events[0]
+			if recorded.EventType != dto.EventTodoCompleted || recorded.EventID == "" {
+				t.Errorf("recorded event type = %q, id = %q", recorded.EventType, recorded.EventID)
+			}
+			if recorded.ContentType != dto.CloudEventsContentType {
+				t.Errorf("recorded content type = %q, want %q", recorded.ContentType, dto.CloudEventsContentType)
+			}
+
+			envelope, err := dto.DecodeEvent(recorded.Payload)
+			if err != nil {
+				t.Fatalf("recorded payload is not a valid event: %v", err)
+			}
+			if envelope.ID != recorded.EventID || envelope.CorrelationID != "corr-1" {
+				t.Errorf("envelope id = %q, correlation id = %q", envelope.ID, envelope.CorrelationID)
+			}
+			var data dto.TodoCompletedData
+			if err := envelope.DecodeData(dto.TodoCompletedDataVersion, &data); err != nil {
+				t.Fatalf("envelope data: %v", err)
+			}
+			if data.TodoID != tt.id {
+				t.Errorf("envelope todo_id = %d, want %d", data.TodoID, tt.id)
+			}
+		})
+	}
+}
+
+func TestTodoUsecase_Complete_Strategies(t *testing.T) {
+	brokerDown := func(ctx context.Context) error { return errors.New("connection refused") }
+	brokerUp := func(ctx context.Context) error { return nil }
+
+	tests := []struct {
+		name          string
+		completion    Completion
+		wait          bool
+		workerApplies bool
+		wantErr       error
+		wantDone      bool
+	}{
+		{
+			name:       "async leaves the todo to the worker",
+			completion: Completion{Strategy: config.CompletionAsync},
+		},
+		{
+			name:       "sync completes the todo",
+			completion: Completion{Strategy: config.CompletionSync},
+			wantDone:   true,
+		},
+		{
+			name:       "async_fallback stays async while the broker is up",
+			completion: Completion{Strategy: config.CompletionAsyncFallback, BrokerUp: brokerUp},
+		},
+		{
+			name:       "async_fallback completes the todo while the broker is down",
+			completion: Completion{Strategy: config.CompletionAsyncFallback, BrokerUp: brokerDown},
+			wantDone:   true,
+		},
+		{
+			name:          "wait returns once the worker has completed the todo",
+			completion:    Completion{Strategy: config.CompletionAsync, WaitTimeout: time.Second},
+			wait:          true,
+			workerApplies: true,
+			wantDone:      true,
+		},
+		{
+			name:       "wait times out while the worker is behind",
+			completion: Completion{Strategy: config.CompletionAsync, WaitTimeout: 150 * time.Millisecond},
+			wait:       true,
+			wantErr:    domain.ErrCompletionPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockTodoRepository{todos: []*domain.Todo{{ID: 1, Title: "Test Todo"}}}
+			if tt.workerApplies {
+				calls := 0
+				repo.getByIDFunc = func(ctx context.Context, id int) (*domain.Todo, error) {
+					calls++
+					todo := &domain.Todo{ID: id, Title: "Test Todo"}
+					if calls > 2 {
+						now := time.Now()
+						todo.DoneAt = &now
+					}
+					return todo, nil
+				}
+			}
+			outboxRepo := &MockOutboxRepository{}
+			processedRepo := &MockProcessedMessageRepository{}
+			mockStore := &MockStore{todoRepo: repo, outboxRepo: outboxRepo, processedRepo: processedRepo}
+			usecase := NewTodoUsecase(mockStore, &MockCache{})
+			usecase.SetCompletion(tt.completion)
+
+			todo, err := usecase.Complete(context.Background(), 1, tt.wait)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TodoUsecase.Complete() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if done := todo.DoneAt != nil; done != tt.wantDone {
+				t.Errorf("TodoUsecase.Complete() done = %v, want %v", done, tt.wantDone)
+			}
+			if len(outboxRepo.events) != 1 {
+				t.Fatalf("TodoUsecase.Complete() recorded %d events, want 1", len(outboxRepo.events))
+			}
+
+			// A synchronously applied event must be skipped by the worker.
+			skipped := processedRepo.processed[completeTodoConsumer+"/"+outboxRepo.events[0].EventID]
+			if applied := tt.wantDone && !tt.workerApplies; skipped != applied {
+				t.Errorf("event marked processed = %v, want %v", skipped, applied)
+			}
+		})
+	}
+}
+
+func TestTodoUsecase_CompleteTodo(t *testing.T) {
+	tests := []struct {
+		name          string
+		eventID       string
+		processed     map[string]bool
+		processedErr  error
+		id            int
+		wantErr       bool
+		wantPermanent bool
+		wantDone      bool
+	}{
+		{
+			name:     "completes the todo",
+			eventID:  "evt-1",
+			id:       1,
+			wantDone: true,
+		},
+		{
+			name:      "skips an event that was already processed",
+			eventID:   "evt-1",
+			processed: map[string]bool{completeTodoConsumer + "/evt-1": true},
+			id:        1,
+		},
+		{
+			name:     "processes events without an ID",
+			id:       1,
+			wantDone: true,
+		},
+		{
+			name:         "fails when the event cannot be recorded",
+			eventID:      "evt-1",
+			processedErr: errors.New("database error"),
+			id:           1,
+			wantErr:      true,
+		},
+		{
+			name:          "todo not found fails permanently",
+			eventID:       "evt-1",
+			id:            2,
+			wantErr:       true,
+			wantPermanent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo := &domain.Todo{ID: 1, Title: "Test Todo"}
+			mockStore := &MockStore{
+				todoRepo:      &MockTodoRepository{todos: []*domain.Todo{todo}},
+				processedRepo: &MockProcessedMessageRepository{processed: tt.processed, err: tt.processedErr},
+			}
+			usecase := NewTodoUsecase(mockStore, &MockCache{})
+
+			err := usecase.CompleteTodo(context.Background(), tt.eventID, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TodoUsecase.CompleteTodo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if domain.IsPermanent(err) != tt.wantPermanent {
+				t.Errorf("TodoUsecase.CompleteTodo() permanent = %v, want %v", domain.IsPermanent(err), tt.wantPermanent)
+			}
+			if done := todo.DoneAt != nil; done != tt.wantDone {
+				t.Errorf("TodoUsecase.CompleteTodo() done = %v, want %v", done, tt.wantDone)
+			}
+		})
+	}
+}
+
+func TestTodoUsecase_Snooze(t *testing.T) {
+	errDatabase := errors.New("database error")
+	until := time.Now().Add(2 * time.Hour)
+	done := time.Now()
+
+	tests := []struct {
+		name         string
+		todo         *domain.Todo
+		id           int
+		scheduleErr  error
+		wantErr      error
+		wantTodo     bool
+		wantSchedule bool
+	}{
+		{
+			name:         "snoozes the todo and schedules the end of the snooze",
+			todo:         &domain.Todo{ID: 1, Title: "Test Todo"},
+			id:           1,
+			wantTodo:     true,
+			wantSchedule: true,
+		},
+		{
+			name:    "completed todos cannot be snoozed",
+			todo:    &domain.Todo{ID: 1, Title: "Test Todo", DoneAt: &done},
+			id:      1,
+			wantErr: domain.ErrTodoCompleted,
+		},
+		{
+			name:    "todo not found",
+			todo:    &domain.Todo{ID: 1, Title: "Test Todo"},
+			id:      2,
+			wantErr: domain.ErrNotFound,
+		},
+		{
+			name:        "fails when the message cannot be scheduled",
+			todo:        &domain.Todo{ID: 1, Title: "Test Todo"},
+			id:          1,
+			scheduleErr: errDatabase,
+			wantErr:     errDatabase,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduled := &MockScheduledMessageRepository{err: tt.scheduleErr}
+			mockStore := &MockStore{
+				todoRepo:      &MockTodoRepository{todos: []*domain.Todo{tt.todo}},
+				scheduledRepo: scheduled,
+			}
+			cache := &MockCache{values: map[string]string{todosCacheKey: "[]"}}
+			usecase := NewTodoUsecase(mockStore, cache)
+
+			todo, err := usecase.Snooze(context.Background(), tt.id, until)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("TodoUsecase.Snooze() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("TodoUsecase.Snooze() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, cached := cache.values[todosCacheKey]; cached != (err != nil) {
+				t.Errorf("todo list cached = %v after Snooze() error %v", cached, err)
+			}
+			if (todo != nil) != tt.wantTodo {
+				t.Fatalf("TodoUsecase.Snooze() todo = %v, want todo %v", todo, tt.wantTodo)
+			}
+			if !tt.wantSchedule {
+				return
+			}
+
+			if todo.SnoozedUntil == nil || !todo.SnoozedUntil.Equal(until.Truncate(time.Second)) {
+				t.Errorf("snoozed until = %v, want %v", todo.SnoozedUntil, until)
+			}
+			if len(scheduled.msgs) != 1 {
+				t.Fatalf("scheduled %d messages, want 1", len(scheduled.msgs))
+			}
+			msg := scheduled.msgs[0]
+			if msg.EventType != dto.EventTodoSnoozeEnded || !msg.DueAt.Equal(*todo.SnoozedUntil) {
+				t.Errorf("scheduled message type = %q, due at %v", msg.EventType, msg.DueAt)
+			}
+		})
+	}
+}
+
+func TestTodoUsecase_EndSnooze(t *testing.T) {
+	until := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	later := until.Add(time.Hour)
+
+	tests := []struct {
+		name         string
+		snoozedUntil *time.Time
+		until        time.Time
+		wantSnoozed  bool
+		wantCached   bool
+	}{
+		{
+			name:         "ends the snooze the event was scheduled for",
+			snoozedUntil: &until,
+			until:        until,
+		},
+		{
+			name:         "ignores an event for an earlier snooze",
+			snoozedUntil: &later,
+			until:        until,
+			wantSnoozed:  true,
+			wantCached:   true,
+		},
+		{
+			name:       "ignores todos that are no longer snoozed",
+			until:      until,
+			wantCached: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo := &domain.Todo{ID: 1, Title: "Test Todo", SnoozedUntil: tt.snoozedUntil}
+			mockStore := &MockStore{todoRepo: &MockTodoRepository{todos: []*domain.Todo{todo}}}
+			cache := &MockCache{values: map[string]string{todosCacheKey: "[]"}}
+			usecase := NewTodoUsecase(mockStore, cache)
+
+			if err := usecase.EndSnooze(context.Background(), "evt-1", 1, tt.until); err != nil {
+				t.Fatalf("TodoUsecase.EndSnooze() error = %v", err)
+			}
+			if snoozed := todo.SnoozedUntil != nil; snoozed != tt.wantSnoozed {
+				t.Errorf("TodoUsecase.EndSnooze() snoozed = %v, want %v", snoozed, tt.wantSnoozed)
+			}
+			if _, cached := cache.values[todosCacheKey]; cached != tt.wantCached {
+				t.Errorf("todo list cached = %v, want %v", cached, tt.wantCached)
+			}
+		})
+	}
+}